@@ -0,0 +1,124 @@
+package arcexporter
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// catalogEntry describes a metric in the metrics catalog
+type catalogEntry struct {
+	name        string
+	description string
+	unit        string
+	metricType  string
+}
+
+// metricsCatalog remembers which metric names were recently written to the catalog
+// measurement of each database so every metric is described once per interval
+type metricsCatalog struct {
+	mu       sync.Mutex
+	interval time.Duration
+	lastSent map[string]time.Time
+}
+
+func newMetricsCatalog(interval time.Duration) *metricsCatalog {
+	return &metricsCatalog{
+		interval: interval,
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// pending returns the distinct metrics (keyed by database and metric name) that have
+// not been written to the database's catalog within the interval
+func (c *metricsCatalog) pending(database string, md pmetric.Metrics) map[string]catalogEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	result := make(map[string]catalogEntry)
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				metric := sm.Metrics().At(k)
+				key := database + "/" + metric.Name()
+				if sentAt, ok := c.lastSent[key]; ok && now.Sub(sentAt) < c.interval {
+					continue
+				}
+				result[key] = catalogEntry{
+					name:        metric.Name(),
+					description: metric.Description(),
+					unit:        metric.Unit(),
+					metricType:  metricTypeName(metric.Type()),
+				}
+			}
+		}
+	}
+	return result
+}
+
+// markSent records the metrics as written and drops expired entries
+func (c *metricsCatalog) markSent(entries map[string]catalogEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, sentAt := range c.lastSent {
+		if now.Sub(sentAt) >= c.interval {
+			delete(c.lastSent, key)
+		}
+	}
+	for key := range entries {
+		c.lastSent[key] = now
+	}
+}
+
+// catalogToColumnar converts pending catalog entries into one row per metric
+func catalogToColumnar(config *Config, entries map[string]catalogEntry) ([]byte, error) {
+	now := toArcTime(pcommon.NewTimestampFromTime(time.Now()), config.TimestampPrecision)
+
+	times := make([]int64, 0, len(entries))
+	names := make([]string, 0, len(entries))
+	descriptions := make([]string, 0, len(entries))
+	units := make([]string, 0, len(entries))
+	metricTypes := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		times = append(times, now)
+		names = append(names, entry.name)
+		descriptions = append(descriptions, entry.description)
+		units = append(units, entry.unit)
+		metricTypes = append(metricTypes, entry.metricType)
+	}
+
+	columns := map[string]interface{}{
+		"time":        times,
+		"metric_name": names,
+		"description": descriptions,
+		"unit":        units,
+		"metric_type": metricTypes,
+	}
+
+	return encodeColumnar(config, config.MetricsCatalogMeasurement, columns)
+}
+
+// metricTypeName returns the metric_type column value of a metric type
+func metricTypeName(t pmetric.MetricType) string {
+	switch t {
+	case pmetric.MetricTypeGauge:
+		return "gauge"
+	case pmetric.MetricTypeSum:
+		return "sum"
+	case pmetric.MetricTypeHistogram:
+		return "histogram"
+	case pmetric.MetricTypeExponentialHistogram:
+		return "exponential_histogram"
+	case pmetric.MetricTypeSummary:
+		return "summary"
+	default:
+		return ""
+	}
+}
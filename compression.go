@@ -0,0 +1,131 @@
+package arcexporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/collector/config/configcompression"
+)
+
+// zstdEncoders holds one encoder per compression level. Encoders are expensive to
+// allocate and safe for concurrent EncodeAll calls, so they are shared by all payloads.
+var (
+	zstdEncodersMu sync.Mutex
+	zstdEncoders   = make(map[int]*zstd.Encoder)
+)
+
+// zstdEncoder returns the shared encoder for a compression level (0 uses the default level)
+func zstdEncoder(level int) (*zstd.Encoder, error) {
+	zstdEncodersMu.Lock()
+	defer zstdEncodersMu.Unlock()
+
+	if encoder, ok := zstdEncoders[level]; ok {
+		return encoder, nil
+	}
+
+	opts := []zstd.EOption{}
+	if level != 0 {
+		opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+	encoder, err := zstd.NewWriter(nil, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+	}
+	zstdEncoders[level] = encoder
+	return encoder, nil
+}
+
+// gzipPools holds a pool of writers per compression level, so payloads reuse the
+// writers' compression state instead of allocating it for every payload
+var (
+	gzipPoolsMu sync.Mutex
+	gzipPools   = make(map[int]*sync.Pool)
+)
+
+// gzipPool returns the writer pool for a compression level
+func gzipPool(level int) (*sync.Pool, error) {
+	gzipPoolsMu.Lock()
+	defer gzipPoolsMu.Unlock()
+
+	if pool, ok := gzipPools[level]; ok {
+		return pool, nil
+	}
+
+	writer, err := gzip.NewWriterLevel(nil, level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+	pool := &sync.Pool{New: func() interface{} {
+		// The level was checked when the pool was created
+		writer, _ := gzip.NewWriterLevel(nil, level)
+		return writer
+	}}
+	pool.Put(writer)
+	gzipPools[level] = pool
+	return pool, nil
+}
+
+// compressPayload encodes a serialized msgpack payload using the configured compression.
+// "none" (or an empty value) returns the payload unchanged.
+func compressPayload(data []byte, compression configcompression.Type, level int) ([]byte, error) {
+	var buf bytes.Buffer
+
+	switch compression {
+	case configcompression.TypeGzip:
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		pool, err := gzipPool(level)
+		if err != nil {
+			return nil, err
+		}
+		gzipWriter := pool.Get().(*gzip.Writer)
+		defer func() {
+			// Do not keep the payload buffer alive while the writer is pooled
+			gzipWriter.Reset(io.Discard)
+			pool.Put(gzipWriter)
+		}()
+
+		gzipWriter.Reset(&buf)
+		if _, err := gzipWriter.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to compress: %w", err)
+		}
+		if err := gzipWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+		}
+	case configcompression.TypeZstd:
+		encoder, err := zstdEncoder(level)
+		if err != nil {
+			return nil, err
+		}
+		return encoder.EncodeAll(data, make([]byte, 0, len(data)/2)), nil
+	case configcompression.TypeSnappy:
+		snappyWriter := snappy.NewBufferedWriter(&buf)
+		if _, err := snappyWriter.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to compress: %w", err)
+		}
+		if err := snappyWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close snappy writer: %w", err)
+		}
+	case "", "none":
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// contentEncoding returns the Content-Encoding header value for the configured compression,
+// or an empty string when the payload is sent uncompressed
+func contentEncoding(compression configcompression.Type) string {
+	if !compression.IsCompressed() {
+		return ""
+	}
+	return string(compression)
+}
@@ -0,0 +1,57 @@
+package arcexporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/collector/config/configcompression"
+)
+
+func TestCompressPayloadRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("arc columnar payload "), 100)
+
+	decompress := map[configcompression.Type]func([]byte) ([]byte, error){
+		configcompression.TypeGzip: func(b []byte) ([]byte, error) {
+			reader, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			return io.ReadAll(reader)
+		},
+		configcompression.TypeZstd: func(b []byte) ([]byte, error) {
+			decoder, err := zstd.NewReader(nil)
+			if err != nil {
+				return nil, err
+			}
+			defer decoder.Close()
+			return decoder.DecodeAll(b, nil)
+		},
+		configcompression.TypeSnappy: func(b []byte) ([]byte, error) {
+			return io.ReadAll(snappy.NewReader(bytes.NewReader(b)))
+		},
+		"none": func(b []byte) ([]byte, error) { return b, nil },
+	}
+
+	for compression, decode := range decompress {
+		for _, level := range []int{0, 1, 9} {
+			// Compress twice so pooled and shared encoders are reused
+			for i := 0; i < 2; i++ {
+				compressed, err := compressPayload(data, compression, level)
+				if err != nil {
+					t.Fatalf("%s level %d: compressPayload: %v", compression, level, err)
+				}
+				got, err := decode(compressed)
+				if err != nil {
+					t.Fatalf("%s level %d: decompress: %v", compression, level, err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Errorf("%s level %d: round trip changed the payload", compression, level)
+				}
+			}
+		}
+	}
+}
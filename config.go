@@ -2,16 +2,111 @@ package arcexporter
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configcompression"
 	"go.opentelemetry.io/collector/config/confighttp"
 	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+)
+
+const (
+	// exponentialHistogramModeNative stores exponential histograms with their native bucket layout
+	exponentialHistogramModeNative = "native"
+	// exponentialHistogramModeExplicit converts exponential histograms to explicit buckets
+	exponentialHistogramModeExplicit = "explicit"
+)
+
+// Supported timestamp precisions for the time column
+const (
+	timestampPrecisionNanoseconds  = "ns"
+	timestampPrecisionMicroseconds = "us"
+	timestampPrecisionMilliseconds = "ms"
+	timestampPrecisionSeconds      = "s"
+)
+
+// Supported attribute storage modes
+const (
+	attributesModeColumns = "columns"
+	attributesModeJSON    = "json"
+	attributesModeHybrid  = "hybrid"
+
+	// attributesColumn is the column holding JSON-serialized attributes
+	attributesColumn = "attributes"
+)
+
+// Supported fallback policies for log records without a timestamp
+const (
+	logTimestampFallbackObserved    = "observed"
+	logTimestampFallbackReceiveTime = "receive_time"
+	logTimestampFallbackDrop        = "drop"
+)
+
+// Supported log body modes
+const (
+	bodyModeString  = "string"
+	bodyModeFlatten = "flatten"
+	bodyModeMap     = "map"
+
+	// defaultBodyMaxDepth is the default nesting depth flattened in "flatten" mode
+	defaultBodyMaxDepth = 3
+)
+
+// Supported target aggregation temporalities for sums and histograms
+const (
+	aggregationTemporalityUnchanged  = "unchanged"
+	aggregationTemporalityCumulative = "cumulative"
+	aggregationTemporalityDelta      = "delta"
+)
+
+// Supported counter rate outputs
+const (
+	counterRateNone        = "none"
+	counterRateColumn      = "column"
+	counterRateMeasurement = "measurement"
+)
+
+// Supported policies for data points without a recorded value and for NaN/±Inf values
+const (
+	valuePolicyDrop = "drop"
+	valuePolicyNull = "null"
+	valuePolicyKeep = "keep"
+)
+
+// MetricMeasurementRule maps metric names matching a regular expression to a shared
+// measurement, e.g. all "system.*" metrics into a "system_metrics" table.
+type MetricMeasurementRule struct {
+	// Match is the regular expression matched against the original metric name
+	Match string `mapstructure:"match"`
+
+	// Measurement is the measurement the matching metrics are written to. It is
+	// sanitized like metric names, e.g. "system.metrics" -> "system_metrics".
+	Measurement string `mapstructure:"measurement"`
+}
+
+// measurementRule is a compiled MetricMeasurementRule
+type measurementRule struct {
+	pattern     *regexp.Regexp
+	measurement string
+}
+
+// Supported histogram and summary encodings
+const (
+	histogramEncodingRows = "rows"
+	histogramEncodingWide = "wide"
 )
 
 // Config defines configuration for Arc exporter.
 type Config struct {
-	confighttp.HTTPClientSettings `mapstructure:",squash"`
-	configretry.BackOffConfig     `mapstructure:"retry_on_failure"`
+	confighttp.ClientConfig   `mapstructure:",squash"`
+	configretry.BackOffConfig `mapstructure:"retry_on_failure"`
+
+	// QueueSettings configures the sending queue. Setting "storage" to a storage extension
+	// (e.g. file_storage) makes the queue persistent across collector restarts.
+	QueueSettings exporterhelper.QueueSettings `mapstructure:"sending_queue"`
 
 	// Endpoint is the Arc API endpoint
 	Endpoint string `mapstructure:"endpoint"`
@@ -32,18 +127,169 @@ type Config struct {
 	// LogsDatabase is the database for logs (optional, defaults to Database)
 	LogsDatabase string `mapstructure:"logs_database"`
 
-	// TracesMeasurement is the measurement name for traces (default: "distributed_traces")
+	// DatabaseRouting routes each resource's telemetry to a database selected by a
+	// resource attribute (optional). Databases above are used as the default.
+	DatabaseRouting DatabaseRoutingConfig `mapstructure:"database_routing"`
+
+	// TracesMeasurement is the measurement name for traces (default: "distributed_traces").
+	// It may contain ${resource.<key>} and ${attr.<key>} placeholders, e.g.
+	// "traces_${resource.service.name}"; templated names are sanitized like metric names.
 	TracesMeasurement string `mapstructure:"traces_measurement"`
 
-	// LogsMeasurement is the measurement name for logs (default: "logs")
+	// TracesMeasurementFallback is used when a traces measurement placeholder cannot be
+	// resolved (default: "distributed_traces")
+	TracesMeasurementFallback string `mapstructure:"traces_measurement_fallback"`
+
+	// ResourcesMeasurement enables resource normalization when set (e.g. "resources"):
+	// each distinct resource is written once per ResourcesRefreshInterval to this
+	// measurement and rows carry only a resource_id column instead of all resource attributes
+	ResourcesMeasurement string `mapstructure:"resources_measurement"`
+
+	// ResourcesRefreshInterval is how often an unchanged resource is re-sent to the
+	// resources measurement (default: 1h)
+	ResourcesRefreshInterval time.Duration `mapstructure:"resources_refresh_interval"`
+
+	// SpanEventsMeasurement is the measurement name for span events such as exceptions
+	// (optional, e.g. "span_events"). Events are not exported when empty.
+	SpanEventsMeasurement string `mapstructure:"span_events_measurement"`
+
+	// SpanLinksMeasurement is the measurement name for span links (optional, e.g. "span_links").
+	// Links are not exported when empty.
+	SpanLinksMeasurement string `mapstructure:"span_links_measurement"`
+
+	// ExemplarsMeasurement is the measurement name for metric exemplars (optional, e.g.
+	// "metric_exemplars"). Each exemplar becomes a row with the metric name, value,
+	// trace_id and span_id. Exemplars are not exported when empty.
+	ExemplarsMeasurement string `mapstructure:"exemplars_measurement"`
+
+	// LogsMeasurement is the measurement name for logs (default: "logs"). It supports the
+	// same placeholders as TracesMeasurement, e.g. "logs_${resource.service.name}".
 	LogsMeasurement string `mapstructure:"logs_measurement"`
 
+	// LogsMeasurementFallback is used when a logs measurement placeholder cannot be
+	// resolved (default: "logs")
+	LogsMeasurementFallback string `mapstructure:"logs_measurement_fallback"`
+
+	// TimestampPrecision is the unit of the time column for all signals: "ns", "us", "ms" or "s"
+	// (default: "ms"). Use "us" or "ns" to keep the ordering of high-resolution data.
+	TimestampPrecision string `mapstructure:"timestamp_precision"`
+
+	// CompressionLevel is the compression level used for gzip (1-9) or zstd (1-22) payloads.
+	// Zero uses the encoder's default level. The algorithm itself is set with "compression"
+	// (gzip, zstd, snappy or none).
+	CompressionLevel int `mapstructure:"compression_level"`
+
+	// LogTimestampFallback controls what happens to log records whose Timestamp is unset:
+	// "observed" (default) uses ObservedTimestamp (or the receive time if that is unset too),
+	// "receive_time" uses the time the exporter received the record, "drop" drops the record
+	LogTimestampFallback string `mapstructure:"log_timestamp_fallback"`
+
+	// BodyMode controls how structured (map) log bodies are stored (default: "string"):
+	// "string" stores the body as a single string, "flatten" flattens map keys into body.*
+	// columns up to BodyMaxDepth levels, "map" keeps the body as a native msgpack map
+	BodyMode string `mapstructure:"body_mode"`
+
+	// BodyMaxDepth is the maximum nesting depth flattened in "flatten" mode (default: 3).
+	// Deeper maps are stored as JSON strings.
+	BodyMaxDepth int `mapstructure:"body_max_depth"`
+
+	// ParseJSONBody treats string bodies holding a JSON object as map bodies in the
+	// "flatten" and "map" body modes. Default: false
+	ParseJSONBody bool `mapstructure:"parse_json_body"`
+
 	// IncludeMetricMetadata controls whether to include internal OTel metadata in labels
 	// (e.g., _monotonic, _aggregation_temporality). Default: false
 	IncludeMetricMetadata bool `mapstructure:"include_metric_metadata"`
 
-	// Note: Metrics do not have a single measurement name. Each metric name becomes
-	// its own measurement/table (e.g., "system.cpu.usage" -> "system_cpu_usage" table)
+	// MetricMetadataColumns adds start_time (data point StartTimestamp), unit and
+	// metric_type (gauge, sum, histogram, exponential_histogram, summary) columns to every
+	// metric row. Default: false
+	MetricMetadataColumns bool `mapstructure:"metric_metadata_columns"`
+
+	// MetricsCatalogMeasurement is the measurement name for the metrics catalog (optional,
+	// e.g. "metrics_catalog"). Each metric name is described (description, unit, type) once
+	// per MetricsCatalogInterval. The catalog is not written when empty.
+	MetricsCatalogMeasurement string `mapstructure:"metrics_catalog_measurement"`
+
+	// MetricsCatalogInterval is how often an already described metric is written to the
+	// catalog again (default: 1h)
+	MetricsCatalogInterval time.Duration `mapstructure:"metrics_catalog_interval"`
+
+	// IncludeScopeAttributes adds instrumentation scope attributes (prefixed with "scope.")
+	// to every row. scope_name and scope_version columns are always included. Default: false
+	IncludeScopeAttributes bool `mapstructure:"include_scope_attributes"`
+
+	// PreserveIntValues writes the exact value of integer data points (int gauges and sums,
+	// histogram and summary counts) to a value_int column in addition to the float64 value
+	// column, so counters above 2^53 stay exact. Default: false
+	PreserveIntValues bool `mapstructure:"preserve_int_values"`
+
+	// AttributesMode controls how attributes are stored (default: "columns"):
+	// "columns" creates one column per attribute key, "json" stores all attributes in a
+	// single JSON "attributes" column, "hybrid" promotes PromotedAttributes to columns and
+	// stores the rest in the "attributes" column
+	AttributesMode string `mapstructure:"attributes_mode"`
+
+	// PromotedAttributes lists the attribute keys stored as columns in "hybrid" mode
+	PromotedAttributes []string `mapstructure:"promoted_attributes"`
+
+	// promotedAttributes is the lookup set built from PromotedAttributes
+	promotedAttributes map[string]bool
+
+	// HistogramEncoding controls how histograms and summaries are stored (default: "rows"):
+	// "rows" writes count, sum, min, max and every bucket/quantile as separate rows,
+	// "wide" writes one row per data point with count, sum, min and max columns and the
+	// bucket bounds/counts (or quantiles/values) as array columns
+	HistogramEncoding string `mapstructure:"histogram_encoding"`
+
+	// HistogramQuantiles lists quantiles (e.g. [0.5, 0.9, 0.99]) estimated from explicit-bucket
+	// histograms by linear interpolation at export time. They are written as p50/p90/p99
+	// columns in the "wide" encoding and as histogram_field=quantile rows otherwise.
+	HistogramQuantiles []float64 `mapstructure:"histogram_quantiles"`
+
+	// AggregationTemporality converts monotonic and non-monotonic sums and explicit-bucket
+	// histograms to one temporality (default: "unchanged"). "delta" turns cumulative series
+	// into differences between consecutive points (the first point of a series only sets
+	// the baseline), "cumulative" accumulates delta series. Counter resets are detected
+	// from StartTimestamp changes and decreasing values. Requires
+	// sending_queue.num_consumers: 1 so the points of a series are converted in order.
+	AggregationTemporality string `mapstructure:"aggregation_temporality"`
+
+	// CounterRate computes per-second rates of monotonic cumulative sums from consecutive
+	// points of each series (default: "none"). "column" adds a rate column to the sum rows,
+	// "measurement" writes the rates to a sibling <metric>_rate measurement. Counter resets
+	// and StartTimestamp changes restart the rate from the new start time. Requires
+	// sending_queue.num_consumers: 1.
+	CounterRate string `mapstructure:"counter_rate"`
+
+	// SeriesStateTTL is how long the per-series state used by AggregationTemporality and
+	// CounterRate is kept for a series that receives no points (default: 10m)
+	SeriesStateTTL time.Duration `mapstructure:"series_state_ttl"`
+
+	// NoRecordedValue controls data points flagged NoRecordedValue, such as Prometheus
+	// staleness markers (default: "drop"): "drop" skips them, "null" writes a row with a
+	// null value, "keep" writes them like any other point. Histogram and summary points
+	// have no single value, so "null" drops them too.
+	NoRecordedValue string `mapstructure:"no_recorded_value"`
+
+	// NonFiniteValues controls NaN and ±Inf values (default: "drop"): "drop" skips the row,
+	// "null" writes a null value, "keep" sends them as is. In the "wide" histogram encoding
+	// a non-finite sum, min, max or percentile column is written as null unless kept.
+	NonFiniteValues string `mapstructure:"non_finite_values"`
+
+	// ExponentialHistogramMode controls how exponential histograms are stored:
+	// "native" (default) keeps scale, zero_count and the positive/negative buckets with
+	// their offsets, "explicit" converts them to explicit "le" buckets like regular histograms
+	ExponentialHistogramMode string `mapstructure:"exponential_histogram_mode"`
+
+	// MetricMeasurements is an ordered list of rules mapping metric names to shared
+	// measurements. The first matching rule wins; metrics in a shared measurement get a
+	// metric_name column. Metrics matching no rule keep their own table.
+	MetricMeasurements []MetricMeasurementRule `mapstructure:"metric_measurements"`
+
+	// Note: Metrics do not have a single measurement name. Unless a MetricMeasurements
+	// rule matches, each metric name becomes its own measurement/table
+	// (e.g., "system.cpu.usage" -> "system_cpu_usage" table)
 }
 
 var _ component.Config = (*Config)(nil)
@@ -54,6 +300,147 @@ func (cfg *Config) Validate() error {
 		return errors.New("endpoint is required")
 	}
 
+	switch cfg.Compression {
+	case "", "none", configcompression.TypeGzip, configcompression.TypeZstd, configcompression.TypeSnappy:
+	default:
+		return fmt.Errorf("unsupported compression %q (supported: gzip, zstd, snappy, none)", cfg.Compression)
+	}
+	if cfg.Compression == configcompression.TypeGzip && (cfg.CompressionLevel < 0 || cfg.CompressionLevel > 9) {
+		return fmt.Errorf("compression_level must be between 1 and 9 for gzip (0 uses the default level), got %d", cfg.CompressionLevel)
+	}
+	if cfg.Compression == configcompression.TypeZstd && (cfg.CompressionLevel < 0 || cfg.CompressionLevel > 22) {
+		return fmt.Errorf("compression_level must be between 1 and 22 for zstd (0 uses the default level), got %d", cfg.CompressionLevel)
+	}
+
+	if cfg.ResourcesRefreshInterval < 0 {
+		return errors.New("resources_refresh_interval must not be negative")
+	}
+	if cfg.ResourcesRefreshInterval == 0 {
+		cfg.ResourcesRefreshInterval = defaultResourcesRefreshInterval
+	}
+
+	if cfg.MetricsCatalogInterval < 0 {
+		return errors.New("metrics_catalog_interval must not be negative")
+	}
+	if cfg.MetricsCatalogInterval == 0 {
+		cfg.MetricsCatalogInterval = defaultMetricsCatalogInterval
+	}
+
+	switch cfg.TimestampPrecision {
+	case "":
+		cfg.TimestampPrecision = timestampPrecisionMilliseconds
+	case timestampPrecisionNanoseconds, timestampPrecisionMicroseconds, timestampPrecisionMilliseconds, timestampPrecisionSeconds:
+	default:
+		return fmt.Errorf("unsupported timestamp_precision %q (supported: ns, us, ms, s)", cfg.TimestampPrecision)
+	}
+
+	switch cfg.LogTimestampFallback {
+	case "":
+		cfg.LogTimestampFallback = logTimestampFallbackObserved
+	case logTimestampFallbackObserved, logTimestampFallbackReceiveTime, logTimestampFallbackDrop:
+	default:
+		return fmt.Errorf("unsupported log_timestamp_fallback %q (supported: observed, receive_time, drop)", cfg.LogTimestampFallback)
+	}
+
+	switch cfg.BodyMode {
+	case "":
+		cfg.BodyMode = bodyModeString
+	case bodyModeString, bodyModeFlatten, bodyModeMap:
+	default:
+		return fmt.Errorf("unsupported body_mode %q (supported: string, flatten, map)", cfg.BodyMode)
+	}
+	if cfg.BodyMaxDepth < 0 {
+		return errors.New("body_max_depth must not be negative")
+	}
+	if cfg.BodyMaxDepth == 0 {
+		cfg.BodyMaxDepth = defaultBodyMaxDepth
+	}
+
+	switch cfg.AttributesMode {
+	case "":
+		cfg.AttributesMode = attributesModeColumns
+	case attributesModeColumns, attributesModeJSON, attributesModeHybrid:
+	default:
+		return fmt.Errorf("unsupported attributes_mode %q (supported: columns, json, hybrid)", cfg.AttributesMode)
+	}
+	cfg.promotedAttributes = make(map[string]bool, len(cfg.PromotedAttributes))
+	for _, key := range cfg.PromotedAttributes {
+		cfg.promotedAttributes[key] = true
+	}
+
+	// The metrics exporter compiles the rules again when it is created
+	if _, err := cfg.metricMeasurementRules(); err != nil {
+		return err
+	}
+
+	switch cfg.HistogramEncoding {
+	case "":
+		cfg.HistogramEncoding = histogramEncodingRows
+	case histogramEncodingRows, histogramEncodingWide:
+	default:
+		return fmt.Errorf("unsupported histogram_encoding %q (supported: rows, wide)", cfg.HistogramEncoding)
+	}
+
+	for _, q := range cfg.HistogramQuantiles {
+		if q < 0 || q > 1 {
+			return fmt.Errorf("histogram_quantiles must be between 0 and 1, got %v", q)
+		}
+	}
+
+	switch cfg.AggregationTemporality {
+	case "":
+		cfg.AggregationTemporality = aggregationTemporalityUnchanged
+	case aggregationTemporalityUnchanged, aggregationTemporalityCumulative, aggregationTemporalityDelta:
+	default:
+		return fmt.Errorf("unsupported aggregation_temporality %q (supported: unchanged, cumulative, delta)", cfg.AggregationTemporality)
+	}
+	switch cfg.CounterRate {
+	case "":
+		cfg.CounterRate = counterRateNone
+	case counterRateNone, counterRateColumn, counterRateMeasurement:
+	default:
+		return fmt.Errorf("unsupported counter_rate %q (supported: none, column, measurement)", cfg.CounterRate)
+	}
+	// Points of a series must be converted in order, which parallel queue consumers break
+	if cfg.QueueSettings.Enabled && cfg.QueueSettings.NumConsumers > 1 {
+		if cfg.AggregationTemporality != aggregationTemporalityUnchanged {
+			return fmt.Errorf("aggregation_temporality %q requires sending_queue.num_consumers to be 1, got %d", cfg.AggregationTemporality, cfg.QueueSettings.NumConsumers)
+		}
+		if cfg.CounterRate != counterRateNone {
+			return fmt.Errorf("counter_rate %q requires sending_queue.num_consumers to be 1, got %d", cfg.CounterRate, cfg.QueueSettings.NumConsumers)
+		}
+	}
+	if cfg.SeriesStateTTL < 0 {
+		return errors.New("series_state_ttl must not be negative")
+	}
+	if cfg.SeriesStateTTL == 0 {
+		cfg.SeriesStateTTL = defaultSeriesStateTTL
+	}
+
+	switch cfg.NoRecordedValue {
+	case "":
+		cfg.NoRecordedValue = valuePolicyDrop
+	case valuePolicyDrop, valuePolicyNull, valuePolicyKeep:
+	default:
+		return fmt.Errorf("unsupported no_recorded_value %q (supported: drop, null, keep)", cfg.NoRecordedValue)
+	}
+
+	switch cfg.NonFiniteValues {
+	case "":
+		cfg.NonFiniteValues = valuePolicyDrop
+	case valuePolicyDrop, valuePolicyNull, valuePolicyKeep:
+	default:
+		return fmt.Errorf("unsupported non_finite_values %q (supported: drop, null, keep)", cfg.NonFiniteValues)
+	}
+
+	switch cfg.ExponentialHistogramMode {
+	case "":
+		cfg.ExponentialHistogramMode = exponentialHistogramModeNative
+	case exponentialHistogramModeNative, exponentialHistogramModeExplicit:
+	default:
+		return fmt.Errorf("unsupported exponential_histogram_mode %q (supported: native, explicit)", cfg.ExponentialHistogramMode)
+	}
+
 	// Set defaults
 	if cfg.Database == "" {
 		cfg.Database = "default"
@@ -76,6 +463,57 @@ func (cfg *Config) Validate() error {
 	if cfg.LogsMeasurement == "" {
 		cfg.LogsMeasurement = "logs"
 	}
+	if cfg.TracesMeasurementFallback == "" {
+		cfg.TracesMeasurementFallback = "distributed_traces"
+	}
+	if cfg.LogsMeasurementFallback == "" {
+		cfg.LogsMeasurementFallback = "logs"
+	}
+
+	// The exporters parse the templates again when they are created
+	if _, err := cfg.tracesMeasurementTemplate(); err != nil {
+		return err
+	}
+	if _, err := cfg.logsMeasurementTemplate(); err != nil {
+		return err
+	}
 
 	return nil
 }
+
+// tracesMeasurementTemplate parses the traces measurement name
+func (cfg *Config) tracesMeasurementTemplate() (*measurementTemplate, error) {
+	template, err := parseMeasurementTemplate(cfg.TracesMeasurement, cfg.TracesMeasurementFallback)
+	if err != nil {
+		return nil, fmt.Errorf("invalid traces_measurement: %w", err)
+	}
+	return template, nil
+}
+
+// logsMeasurementTemplate parses the logs measurement name
+func (cfg *Config) logsMeasurementTemplate() (*measurementTemplate, error) {
+	template, err := parseMeasurementTemplate(cfg.LogsMeasurement, cfg.LogsMeasurementFallback)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logs_measurement: %w", err)
+	}
+	return template, nil
+}
+
+// metricMeasurementRules compiles the metric_measurements rules
+func (cfg *Config) metricMeasurementRules() ([]measurementRule, error) {
+	rules := make([]measurementRule, 0, len(cfg.MetricMeasurements))
+	for i, rule := range cfg.MetricMeasurements {
+		if rule.Measurement == "" {
+			return nil, fmt.Errorf("metric_measurements[%d]: measurement is required", i)
+		}
+		pattern, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("metric_measurements[%d]: invalid match expression: %w", i, err)
+		}
+		rules = append(rules, measurementRule{
+			pattern:     pattern,
+			measurement: sanitizeMetricName(rule.Measurement),
+		})
+	}
+	return rules, nil
+}
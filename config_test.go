@@ -0,0 +1,50 @@
+package arcexporter
+
+import (
+	"testing"
+)
+
+func TestValidateSeriesStateRequiresSingleConsumer(t *testing.T) {
+	tests := []struct {
+		name         string
+		modify       func(cfg *Config)
+		numConsumers int
+		wantErr      bool
+	}{
+		{
+			name:         "no series state with parallel consumers",
+			modify:       func(cfg *Config) {},
+			numConsumers: 10,
+		},
+		{
+			name:         "temporality with parallel consumers",
+			modify:       func(cfg *Config) { cfg.AggregationTemporality = aggregationTemporalityDelta },
+			numConsumers: 10,
+			wantErr:      true,
+		},
+		{
+			name:         "counter rate with parallel consumers",
+			modify:       func(cfg *Config) { cfg.CounterRate = counterRateColumn },
+			numConsumers: 10,
+			wantErr:      true,
+		},
+		{
+			name:         "temporality with one consumer",
+			modify:       func(cfg *Config) { cfg.AggregationTemporality = aggregationTemporalityDelta },
+			numConsumers: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := createDefaultConfig().(*Config)
+			cfg.Endpoint = "http://localhost:8000"
+			cfg.QueueSettings.NumConsumers = tt.numConsumers
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
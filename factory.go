@@ -17,6 +17,15 @@ const (
 
 	// defaultTimeout is the default HTTP timeout
 	defaultTimeout = 30 * time.Second
+
+	// defaultResourcesRefreshInterval is how often unchanged normalized resources are re-sent
+	defaultResourcesRefreshInterval = time.Hour
+
+	// defaultMetricsCatalogInterval is how often described metrics are re-sent to the catalog
+	defaultMetricsCatalogInterval = time.Hour
+
+	// defaultSeriesStateTTL is how long per-series metric state is kept without new points
+	defaultSeriesStateTTL = 10 * time.Minute
 )
 
 // NewFactory creates a factory for Arc exporter.
@@ -32,14 +41,31 @@ func NewFactory() exporter.Factory {
 
 func createDefaultConfig() component.Config {
 	return &Config{
-		HTTPClientSettings: confighttp.HTTPClientSettings{
+		ClientConfig: confighttp.ClientConfig{
 			Timeout:     defaultTimeout,
 			Compression: "gzip",
 		},
-		BackOffConfig:     configretry.NewDefaultBackOffConfig(),
-		Database:          "default",
-		TracesMeasurement: "distributed_traces",
-		LogsMeasurement:   "logs",
+		BackOffConfig:             configretry.NewDefaultBackOffConfig(),
+		QueueSettings:             exporterhelper.NewDefaultQueueSettings(),
+		Database:                  "default",
+		TracesMeasurement:         "distributed_traces",
+		LogsMeasurement:           "logs",
+		TracesMeasurementFallback: "distributed_traces",
+		LogsMeasurementFallback:   "logs",
+		TimestampPrecision:        timestampPrecisionMilliseconds,
+		AttributesMode:            attributesModeColumns,
+		LogTimestampFallback:      logTimestampFallbackObserved,
+		BodyMode:                  bodyModeString,
+		BodyMaxDepth:              defaultBodyMaxDepth,
+		ResourcesRefreshInterval:  defaultResourcesRefreshInterval,
+		MetricsCatalogInterval:    defaultMetricsCatalogInterval,
+		HistogramEncoding:         histogramEncodingRows,
+		AggregationTemporality:    aggregationTemporalityUnchanged,
+		CounterRate:               counterRateNone,
+		SeriesStateTTL:            defaultSeriesStateTTL,
+		NoRecordedValue:           valuePolicyDrop,
+		NonFiniteValues:           valuePolicyDrop,
+		ExponentialHistogramMode:  exponentialHistogramModeNative,
 	}
 }
 
@@ -49,16 +75,20 @@ func createTracesExporter(
 	cfg component.Config,
 ) (exporter.Traces, error) {
 	c := cfg.(*Config)
-	exp := newTracesExporter(c, set)
+	exp, err := newTracesExporter(c, set)
+	if err != nil {
+		return nil, err
+	}
 
 	return exporterhelper.NewTracesExporter(
 		ctx,
 		set,
 		cfg,
 		exp.pushTraces,
+		exporterhelper.WithStart(exp.start),
 		exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{Timeout: c.Timeout}),
 		exporterhelper.WithRetry(c.BackOffConfig),
-		exporterhelper.WithQueue(exporterhelper.NewDefaultQueueSettings()),
+		exporterhelper.WithQueue(c.QueueSettings),
 	)
 }
 
@@ -68,16 +98,20 @@ func createMetricsExporter(
 	cfg component.Config,
 ) (exporter.Metrics, error) {
 	c := cfg.(*Config)
-	exp := newMetricsExporter(c, set)
+	exp, err := newMetricsExporter(c, set)
+	if err != nil {
+		return nil, err
+	}
 
 	return exporterhelper.NewMetricsExporter(
 		ctx,
 		set,
 		cfg,
 		exp.pushMetrics,
+		exporterhelper.WithStart(exp.start),
 		exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{Timeout: c.Timeout}),
 		exporterhelper.WithRetry(c.BackOffConfig),
-		exporterhelper.WithQueue(exporterhelper.NewDefaultQueueSettings()),
+		exporterhelper.WithQueue(c.QueueSettings),
 	)
 }
 
@@ -87,15 +121,19 @@ func createLogsExporter(
 	cfg component.Config,
 ) (exporter.Logs, error) {
 	c := cfg.(*Config)
-	exp := newLogsExporter(c, set)
+	exp, err := newLogsExporter(c, set)
+	if err != nil {
+		return nil, err
+	}
 
 	return exporterhelper.NewLogsExporter(
 		ctx,
 		set,
 		cfg,
 		exp.pushLogs,
+		exporterhelper.WithStart(exp.start),
 		exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{Timeout: c.Timeout}),
 		exporterhelper.WithRetry(c.BackOffConfig),
-		exporterhelper.WithQueue(exporterhelper.NewDefaultQueueSettings()),
+		exporterhelper.WithQueue(c.QueueSettings),
 	)
 }
@@ -2,59 +2,200 @@ package arcexporter
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
+	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/vmihailenco/msgpack/v5"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.uber.org/zap"
 )
 
 type logsExporter struct {
-	config *Config
-	client *http.Client
-	logger *zap.Logger
+	config   *Config
+	client   *http.Client
+	logger   *zap.Logger
+	settings component.TelemetrySettings
+
+	// resources caches normalized resources (nil unless resources_measurement is set)
+	resources *resourceCache
+
+	// router resolves per-resource databases (nil unless database_routing is set)
+	router *databaseRouter
+
+	// measurement resolves the measurement name of each log record
+	measurement *measurementTemplate
 }
 
-func newLogsExporter(config *Config, set exporter.CreateSettings) *logsExporter {
-	return &logsExporter{
-		config: config,
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
-		logger: set.Logger,
+func newLogsExporter(config *Config, set exporter.CreateSettings) (*logsExporter, error) {
+	measurement, err := config.logsMeasurementTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &logsExporter{
+		config:      config,
+		logger:      set.Logger,
+		settings:    set.TelemetrySettings,
+		router:      newDatabaseRouter(config.DatabaseRouting, config.LogsDatabase),
+		measurement: measurement,
+	}
+	if config.ResourcesMeasurement != "" {
+		exp.resources = newResourceCache(config.ResourcesRefreshInterval)
 	}
+	return exp, nil
 }
 
-func (e *logsExporter) pushLogs(ctx context.Context, ld plog.Logs) error {
-	// Convert OTel logs to Arc columnar format
-	payload, err := e.logsToColumnar(ld)
+// start prepares compression and creates the HTTP client
+func (e *logsExporter) start(_ context.Context, host component.Host) error {
+	client, err := startClient(e.config, host, e.settings)
 	if err != nil {
-		return fmt.Errorf("failed to convert logs: %w", err)
+		return err
+	}
+	e.client = client
+	return nil
+}
+
+func (e *logsExporter) pushLogs(ctx context.Context, ld plog.Logs) error {
+	// Without routing rules everything goes to the logs database
+	if e.router == nil {
+		return e.pushLogsToDatabase(ctx, e.config.LogsDatabase, ld)
+	}
+
+	routed := splitByDatabase(e.router, e.resourcesOf(ld), plog.NewLogs, func(dest plog.Logs, i int) {
+		ld.ResourceLogs().At(i).CopyTo(dest.ResourceLogs().AppendEmpty())
+	})
+	retry, err := pushRouted(ctx, routed, e.pushLogsToDatabase)
+	if len(retry) == 0 {
+		return err
+	}
+
+	// Only the resources of databases that failed with a retryable error are retried
+	failed := plog.NewLogs()
+	for _, database := range retry {
+		routed[database].ResourceLogs().MoveAndAppendTo(failed.ResourceLogs())
+	}
+	return consumererror.NewLogs(err, failed)
+}
+
+// resourcesOf returns the resource of each resource logs in the batch
+func (e *logsExporter) resourcesOf(ld plog.Logs) []pcommon.Resource {
+	resources := make([]pcommon.Resource, 0, ld.ResourceLogs().Len())
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		resources = append(resources, ld.ResourceLogs().At(i).Resource())
+	}
+	return resources
+}
+
+func (e *logsExporter) pushLogsToDatabase(ctx context.Context, database string, ld plog.Logs) error {
+	// Send new or expired resources first so rows never reference an unknown resource
+	if e.resources != nil {
+		if err := sendResources(ctx, e.config, e.resources, database, e.resourcesOf(ld), e.sendToArc); err != nil {
+			return err
+		}
+	}
+
+	// Convert OTel logs to Arc columnar format, one payload per measurement
+	for measurement, logs := range e.splitByMeasurement(ld) {
+		payload, err := e.logsToColumnar(logs, measurement)
+		if err != nil {
+			return fmt.Errorf("failed to convert logs: %w", err)
+		}
+
+		// Nothing to send if every record was dropped
+		if payload == nil {
+			continue
+		}
+
+		// Send to Arc
+		if err := e.sendToArc(ctx, database, payload); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitByMeasurement groups log records by the measurement resolved from the logs
+// measurement template. Static measurement names return the logs unchanged.
+func (e *logsExporter) splitByMeasurement(ld plog.Logs) map[string]plog.Logs {
+	template := e.measurement
+	if template.static {
+		return map[string]plog.Logs{template.fallback: ld}
 	}
 
-	// Send to Arc
-	return e.sendToArc(ctx, payload)
+	result := make(map[string]plog.Logs)
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		destResources := make(map[string]plog.ResourceLogs)
+
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			destScopes := make(map[string]plog.ScopeLogs)
+
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				lr := sl.LogRecords().At(k)
+				measurement := template.resolve(rl.Resource().Attributes(), lr.Attributes())
+
+				// Copy resource and scope once per measurement, then the record itself
+				destScope, ok := destScopes[measurement]
+				if !ok {
+					destResource, ok := destResources[measurement]
+					if !ok {
+						logs, ok := result[measurement]
+						if !ok {
+							logs = plog.NewLogs()
+							result[measurement] = logs
+						}
+						destResource = logs.ResourceLogs().AppendEmpty()
+						rl.Resource().CopyTo(destResource.Resource())
+						destResource.SetSchemaUrl(rl.SchemaUrl())
+						destResources[measurement] = destResource
+					}
+					destScope = destResource.ScopeLogs().AppendEmpty()
+					sl.Scope().CopyTo(destScope.Scope())
+					destScope.SetSchemaUrl(sl.SchemaUrl())
+					destScopes[measurement] = destScope
+				}
+				lr.CopyTo(destScope.LogRecords().AppendEmpty())
+			}
+		}
+	}
+	return result
 }
 
-func (e *logsExporter) logsToColumnar(ld plog.Logs) ([]byte, error) {
+// logsToColumnar converts logs to Arc columnar format. It returns nil if no
+// records are left after applying the timestamp fallback policy.
+func (e *logsExporter) logsToColumnar(ld plog.Logs, measurement string) ([]byte, error) {
+	// Receive time used for records without timestamps
+	receiveTime := pcommon.NewTimestampFromTime(time.Now())
+	dropped := 0
+
 	// Columnar arrays for fixed fields
 	times := []int64{}
+	observedTimes := []interface{}{}
 	severities := []string{}
 	severityNumbers := []int32{}
-	bodies := []string{}
+	bodies := []interface{}{}
 	traceIDs := []string{}
 	spanIDs := []string{}
 	traceFlags := []uint32{}
 	serviceNames := []string{}
+	scopeNames := []string{}
+	scopeVersions := []string{}
 
 	// Collect all attributes for dynamic columns
 	allAttributes := []map[string]interface{}{}
 
+	// The resource ID and flattened body keys are always stored as columns
+	fixedKeys := map[string]bool{resourceIDColumn: true}
+
 	// Iterate through resource logs
 	for i := 0; i < ld.ResourceLogs().Len(); i++ {
 		rl := ld.ResourceLogs().At(i)
@@ -62,25 +203,69 @@ func (e *logsExporter) logsToColumnar(ld plog.Logs) ([]byte, error) {
 		// Resource attributes
 		resAttrs := attributesToMap(rl.Resource().Attributes())
 
+		// With normalized resources, rows only carry the resource ID
+		rowResAttrs := resAttrs
+		if e.resources != nil {
+			rowResAttrs = map[string]interface{}{resourceIDColumn: resourceID(resAttrs)}
+		}
+
 		// Iterate through scope logs
 		for j := 0; j < rl.ScopeLogs().Len(); j++ {
 			sl := rl.ScopeLogs().At(j)
 
+			// Scope attributes are only included if requested
+			baseAttrs := rowResAttrs
+			if e.config.IncludeScopeAttributes {
+				baseAttrs = mergeAttributes(rowResAttrs, scopeAttributesToMap(sl.Scope()))
+			}
+
 			// Iterate through log records
 			for k := 0; k < sl.LogRecords().Len(); k++ {
 				lr := sl.LogRecords().At(k)
 
-				// Time (Arc expects milliseconds)
-				times = append(times, lr.Timestamp().AsTime().UnixMilli())
+				// Many sources (filelog, syslog) leave Timestamp unset, apply the fallback policy
+				timestamp := lr.Timestamp()
+				if timestamp == 0 {
+					switch e.config.LogTimestampFallback {
+					case logTimestampFallbackDrop:
+						dropped++
+						continue
+					case logTimestampFallbackReceiveTime:
+						timestamp = receiveTime
+					default:
+						timestamp = lr.ObservedTimestamp()
+						if timestamp == 0 {
+							timestamp = receiveTime
+						}
+					}
+				}
+
+				// Time in the configured precision
+				times = append(times, toArcTime(timestamp, e.config.TimestampPrecision))
+
+				// Unset observed timestamps are null rather than the epoch
+				if lr.ObservedTimestamp() == 0 {
+					observedTimes = append(observedTimes, nil)
+				} else {
+					observedTimes = append(observedTimes, toArcTime(lr.ObservedTimestamp(), e.config.TimestampPrecision))
+				}
 
 				// Severity
 				severities = append(severities, lr.SeverityText())
 				severityNumbers = append(severityNumbers, int32(lr.SeverityNumber()))
 
 				// Body
-				body := ""
-				switch lr.Body().Type() {
-				case 1: // String
+				var body interface{}
+				var bodyFields map[string]interface{}
+				structuredBody, isStructured := e.structuredBody(lr.Body())
+				switch {
+				case isStructured && e.config.BodyMode == bodyModeFlatten:
+					// Map keys become body.* columns, the body column stays empty
+					bodyFields = make(map[string]interface{})
+					flattenBody("body", structuredBody, 1, e.config.BodyMaxDepth, bodyFields)
+				case isStructured && e.config.BodyMode == bodyModeMap:
+					body = structuredBody
+				case lr.Body().Type() == pcommon.ValueTypeStr:
 					body = lr.Body().Str()
 				default:
 					body = lr.Body().AsString()
@@ -111,28 +296,35 @@ func (e *logsExporter) logsToColumnar(ld plog.Logs) ([]byte, error) {
 				}
 				serviceNames = append(serviceNames, serviceName)
 
+				// Instrumentation scope
+				scopeNames = append(scopeNames, sl.Scope().Name())
+				scopeVersions = append(scopeVersions, sl.Scope().Version())
+
 				// Merge resource attributes with log attributes
 				logAttrs := attributesToMap(lr.Attributes())
-				mergedAttrs := mergeAttributes(resAttrs, logAttrs)
+				mergedAttrs := mergeAttributes(baseAttrs, logAttrs)
+				// Skip service.name since we already have service_name column
+				delete(mergedAttrs, "service.name")
+				for key, val := range bodyFields {
+					mergedAttrs[key] = val
+					fixedKeys[key] = true
+				}
 				allAttributes = append(allAttributes, mergedAttrs)
 			}
 		}
 	}
 
-	// Dynamically extract all unique attribute keys
-	attributeKeys := make(map[string]bool)
-	for _, attrs := range allAttributes {
-		for key := range attrs {
-			// Skip service.name since we already have service_name column
-			if key != "service.name" {
-				attributeKeys[key] = true
-			}
-		}
+	if dropped > 0 {
+		e.logger.Debug("Dropped log records without timestamp", zap.Int("count", dropped))
+	}
+	if len(times) == 0 {
+		return nil, nil
 	}
 
 	// Create columns map with fixed fields
 	columns := map[string]interface{}{
 		"time":            times,
+		"observed_time":   observedTimes,
 		"severity":        severities,
 		"severity_number": severityNumbers,
 		"body":            bodies,
@@ -140,47 +332,62 @@ func (e *logsExporter) logsToColumnar(ld plog.Logs) ([]byte, error) {
 		"span_id":         spanIDs,
 		"trace_flags":     traceFlags,
 		"service_name":    serviceNames,
+		"scope_name":      scopeNames,
+		"scope_version":   scopeVersions,
 	}
 
-	// Add dynamic columns for each attribute
-	for attrKey := range attributeKeys {
-		columnValues := make([]interface{}, len(allAttributes))
-		for i, attrs := range allAttributes {
-			if val, ok := attrs[attrKey]; ok {
-				columnValues[i] = val
-			} else {
-				columnValues[i] = nil
-			}
-		}
-		columns[attrKey] = columnValues
-	}
+	// Add attribute columns according to the attributes mode
+	addAttributeColumns(e.config, columns, allAttributes, fixedKeys)
 
-	// Create columnar payload
-	columnarData := map[string]interface{}{
-		"m":       e.config.LogsMeasurement,
-		"columns": columns,
-	}
+	return encodeColumnar(e.config, measurement, columns)
+}
 
-	// Serialize to msgpack
-	msgpackData, err := msgpack.Marshal(columnarData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal msgpack: %w", err)
+// structuredBody returns the body as a map if it is a map body or, when parse_json_body
+// is enabled, a string body holding a JSON object. It always returns false in "string" mode.
+func (e *logsExporter) structuredBody(body pcommon.Value) (map[string]interface{}, bool) {
+	if e.config.BodyMode == bodyModeString || e.config.BodyMode == "" {
+		return nil, false
 	}
 
-	// Compress with gzip
-	var buf bytes.Buffer
-	gzipWriter := gzip.NewWriter(&buf)
-	if _, err := gzipWriter.Write(msgpackData); err != nil {
-		return nil, fmt.Errorf("failed to compress: %w", err)
-	}
-	if err := gzipWriter.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+	switch body.Type() {
+	case pcommon.ValueTypeMap:
+		return attributesToMap(body.Map()), true
+	case pcommon.ValueTypeStr:
+		if !e.config.ParseJSONBody {
+			return nil, false
+		}
+		str := strings.TrimSpace(body.Str())
+		if !strings.HasPrefix(str, "{") {
+			return nil, false
+		}
+		var parsed map[string]interface{}
+		if err := json.Unmarshal([]byte(str), &parsed); err != nil {
+			return nil, false
+		}
+		return parsed, true
+	default:
+		return nil, false
 	}
+}
 
-	return buf.Bytes(), nil
+// flattenBody flattens a map body into prefix-joined keys (e.g. "body.http.status")
+// up to maxDepth levels. Maps nested deeper are stored as JSON strings.
+func flattenBody(prefix string, m map[string]interface{}, depth, maxDepth int, out map[string]interface{}) {
+	for key, val := range m {
+		fullKey := prefix + "." + key
+		nested, isMap := val.(map[string]interface{})
+		switch {
+		case isMap && depth < maxDepth:
+			flattenBody(fullKey, nested, depth+1, maxDepth, out)
+		case isMap:
+			out[fullKey] = attributesToJSON(nested)
+		default:
+			out[fullKey] = val
+		}
+	}
 }
 
-func (e *logsExporter) sendToArc(ctx context.Context, payload []byte) error {
+func (e *logsExporter) sendToArc(ctx context.Context, database string, payload []byte) error {
 	url := fmt.Sprintf("%s/api/v1/write/msgpack", e.config.Endpoint)
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
@@ -189,8 +396,11 @@ func (e *logsExporter) sendToArc(ctx context.Context, payload []byte) error {
 	}
 
 	req.Header.Set("Content-Type", "application/msgpack")
-	req.Header.Set("Content-Encoding", "gzip")
-	req.Header.Set("X-Arc-Database", e.config.LogsDatabase)
+	if encoding := contentEncoding(e.config.Compression); encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	req.Header.Set("X-Arc-Timestamp-Precision", e.config.TimestampPrecision)
+	req.Header.Set("X-Arc-Database", database)
 
 	if e.config.AuthToken != "" {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.config.AuthToken))
@@ -202,9 +412,8 @@ func (e *logsExporter) sendToArc(ctx context.Context, payload []byte) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("arc returned status %d: %s", resp.StatusCode, string(body))
+	if err := checkArcResponse(resp, e.logger); err != nil {
+		return err
 	}
 
 	e.logger.Debug("Successfully sent logs to Arc",
@@ -2,38 +2,160 @@ package arcexporter
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
 	"fmt"
-	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
-	"github.com/vmihailenco/msgpack/v5"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.uber.org/zap"
 )
 
 type metricsExporter struct {
-	config *Config
-	client *http.Client
-	logger *zap.Logger
+	config   *Config
+	client   *http.Client
+	logger   *zap.Logger
+	settings component.TelemetrySettings
+
+	// resources caches normalized resources (nil unless resources_measurement is set)
+	resources *resourceCache
+
+	// router resolves per-resource databases (nil unless database_routing is set)
+	router *databaseRouter
+
+	// catalog tracks metrics written to the metrics catalog (nil unless metrics_catalog_measurement is set)
+	catalog *metricsCatalog
+
+	// rates tracks counter series for rate computation (nil unless counter_rate is set)
+	rates *rateTracker
+
+	// temporality converts sums and histograms (nil unless aggregation_temporality is set)
+	temporality *temporalityConverter
+
+	// measurementRules are the compiled metric_measurements rules
+	measurementRules []measurementRule
+}
+
+func newMetricsExporter(config *Config, set exporter.CreateSettings) (*metricsExporter, error) {
+	measurementRules, err := config.metricMeasurementRules()
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &metricsExporter{
+		config:           config,
+		logger:           set.Logger,
+		settings:         set.TelemetrySettings,
+		router:           newDatabaseRouter(config.DatabaseRouting, config.MetricsDatabase),
+		temporality:      newTemporalityConverter(config.AggregationTemporality, config.SeriesStateTTL),
+		measurementRules: measurementRules,
+	}
+	if config.ResourcesMeasurement != "" {
+		exp.resources = newResourceCache(config.ResourcesRefreshInterval)
+	}
+
+	if config.MetricsCatalogMeasurement != "" {
+		exp.catalog = newMetricsCatalog(config.MetricsCatalogInterval)
+	}
+	if config.CounterRate != counterRateNone {
+		exp.rates = newRateTracker(config.SeriesStateTTL)
+	}
+	return exp, nil
 }
 
-func newMetricsExporter(config *Config, set exporter.CreateSettings) *metricsExporter {
-	return &metricsExporter{
-		config: config,
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
-		logger: set.Logger,
+// start prepares compression and creates the HTTP client
+func (e *metricsExporter) start(_ context.Context, host component.Host) error {
+	client, err := startClient(e.config, host, e.settings)
+	if err != nil {
+		return err
 	}
+	e.client = client
+	return nil
 }
 
 func (e *metricsExporter) pushMetrics(ctx context.Context, md pmetric.Metrics) error {
+	// Without routing rules everything goes to the metrics database
+	if e.router == nil {
+		return e.pushMetricsToDatabase(ctx, e.config.MetricsDatabase, md)
+	}
+
+	routed := splitByDatabase(e.router, e.resourcesOf(md), pmetric.NewMetrics, func(dest pmetric.Metrics, i int) {
+		md.ResourceMetrics().At(i).CopyTo(dest.ResourceMetrics().AppendEmpty())
+	})
+	retry, err := pushRouted(ctx, routed, e.pushMetricsToDatabase)
+	if len(retry) == 0 {
+		return err
+	}
+
+	// Only the resources of databases that failed with a retryable error are retried
+	failed := pmetric.NewMetrics()
+	for _, database := range retry {
+		routed[database].ResourceMetrics().MoveAndAppendTo(failed.ResourceMetrics())
+	}
+	return consumererror.NewMetrics(err, failed)
+}
+
+// resourcesOf returns the resource of each resource metrics in the batch
+func (e *metricsExporter) resourcesOf(md pmetric.Metrics) []pcommon.Resource {
+	resources := make([]pcommon.Resource, 0, md.ResourceMetrics().Len())
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		resources = append(resources, md.ResourceMetrics().At(i).Resource())
+	}
+	return resources
+}
+
+func (e *metricsExporter) pushMetricsToDatabase(ctx context.Context, database string, md pmetric.Metrics) error {
+	// Send new or expired resources first so rows never reference an unknown resource
+	if e.resources != nil {
+		if err := sendResources(ctx, e.config, e.resources, database, e.resourcesOf(md), e.sendToArc); err != nil {
+			return err
+		}
+	}
+
+	// Describe metrics not recently written to the catalog
+	if e.catalog != nil {
+		if err := e.sendCatalog(ctx, database, md); err != nil {
+			return err
+		}
+	}
+
+	// Forget series that stopped reporting
+	if e.temporality != nil {
+		e.temporality.prune()
+	}
+	if e.rates != nil {
+		e.rates.prune()
+	}
+
+	// Series state changes are only committed once every payload was sent
+	updates := &seriesUpdates{
+		temporality: make(temporalityUpdates),
+		rates:       make(rateUpdates),
+	}
+
 	// Group metrics by name (each metric name becomes a separate measurement/table)
 	metricGroups := make(map[string]*metricBatch)
+	batchFor := func(name string) *metricBatch {
+		batch, ok := metricGroups[name]
+		if !ok {
+			batch = &metricBatch{
+				name:      name,
+				times:     []int64{},
+				values:    []interface{}{},
+				labels:    []map[string]interface{}{},
+				fields:    []map[string]interface{}{},
+				nonFinite: e.config.NonFiniteValues,
+			}
+			metricGroups[name] = batch
+		}
+		return batch
+	}
 
 	// Iterate through resource metrics
 	for i := 0; i < md.ResourceMetrics().Len(); i++ {
@@ -42,274 +164,705 @@ func (e *metricsExporter) pushMetrics(ctx context.Context, md pmetric.Metrics) e
 		// Extract resource attributes (host.name, service.name, etc.)
 		resourceAttrs := attributesToMap(rm.Resource().Attributes())
 
+		// With normalized resources, data points only carry the resource ID
+		resourceFields := map[string]interface{}{}
+		if e.resources != nil {
+			resourceFields[resourceIDColumn] = resourceID(resourceAttrs)
+			resourceAttrs = map[string]interface{}{}
+		}
+
 		// Iterate through scope metrics
 		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
 			sm := rm.ScopeMetrics().At(j)
 
+			// Instrumentation scope columns (and scope attributes if requested)
+			scopeFields := mergeAttributes(resourceFields, map[string]interface{}{
+				"scope_name":    sm.Scope().Name(),
+				"scope_version": sm.Scope().Version(),
+			})
+			baseAttrs := resourceAttrs
+			if e.config.IncludeScopeAttributes {
+				baseAttrs = mergeAttributes(resourceAttrs, scopeAttributesToMap(sm.Scope()))
+			}
+
 			// Iterate through metrics
 			for k := 0; k < sm.Metrics().Len(); k++ {
 				metric := sm.Metrics().At(k)
-				metricName := sanitizeMetricName(metric.Name())
+				metricName, shared := e.measurementForMetric(metric.Name())
 
-				// Get or create batch for this metric name
-				batch, ok := metricGroups[metricName]
-				if !ok {
-					batch = &metricBatch{
-						name:   metricName,
-						times:  []int64{},
-						values: []float64{},
-						labels: []map[string]interface{}{},
-					}
-					metricGroups[metricName] = batch
+				// Metrics sharing a measurement are told apart by a metric_name column
+				metricFields := scopeFields
+				if shared {
+					metricFields = mergeAttributes(scopeFields, map[string]interface{}{"metric_name": metric.Name()})
 				}
 
-				// Process based on metric type (pass resource attributes)
+				// Unit and metric type columns when metadata columns are enabled
+				if e.config.MetricMetadataColumns {
+					metricFields = mergeAttributes(metricFields, map[string]interface{}{
+						"unit":        metric.Unit(),
+						"metric_type": metricTypeName(metric.Type()),
+					})
+				}
+
+				// Get or create batch for this metric name
+				batch := batchFor(metricName)
+
+				// Process based on metric type (pass the attributes and exporter columns)
 				switch metric.Type() {
 				case pmetric.MetricTypeGauge:
-					e.processGauge(metric, batch, resourceAttrs)
+					e.processGauge(metric, batch, baseAttrs, metricFields)
 				case pmetric.MetricTypeSum:
-					e.processSum(metric, batch, resourceAttrs)
+					// Counter rates may go to a sibling <metric>_rate measurement
+					var rates *metricBatch
+					if e.config.CounterRate == counterRateMeasurement && isCumulativeCounter(metric.Sum()) {
+						rates = batchFor(metricName + "_rate")
+					}
+					e.processSum(metric, batch, rates, updates, baseAttrs, metricFields)
 				case pmetric.MetricTypeHistogram:
-					e.processHistogram(metric, batch, resourceAttrs)
+					e.processHistogram(metric, batch, updates, baseAttrs, metricFields)
+				case pmetric.MetricTypeExponentialHistogram:
+					e.processExponentialHistogram(metric, batch, baseAttrs, metricFields)
 				case pmetric.MetricTypeSummary:
-					e.processSummary(metric, batch, resourceAttrs)
+					e.processSummary(metric, batch, baseAttrs, metricFields)
+				}
+
+				// Exemplars go to their own measurement when configured
+				if e.config.ExemplarsMeasurement != "" {
+					e.processExemplars(metric, batchFor(e.config.ExemplarsMeasurement), baseAttrs, scopeFields)
 				}
 			}
 		}
 	}
 
-	// Send each metric group as a separate payload
+	// Send each metric group as a separate payload. A group rejected by Arc is dropped
+	// without holding back the others.
+	var errs []error
 	for metricName, batch := range metricGroups {
+		// All points may have been held back as temporality or rate baselines
+		if len(batch.times) == 0 {
+			continue
+		}
+
 		payload, err := e.batchToColumnar(metricName, batch)
 		if err != nil {
-			return fmt.Errorf("failed to convert metric %s: %w", metricName, err)
+			errs = append(errs, fmt.Errorf("failed to convert metric %s: %w", metricName, err))
+			continue
 		}
 
-		if err := e.sendToArc(ctx, payload); err != nil {
-			return fmt.Errorf("failed to send metric %s: %w", metricName, err)
+		if err := e.sendToArc(ctx, database, payload); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send metric %s: %w", metricName, err))
 		}
 	}
 
-	return nil
+	// A retried batch must be converted from the same state; dropped groups are not retried
+	err := joinSendErrors(errs)
+	if err != nil && !consumererror.IsPermanent(err) {
+		return err
+	}
+	if e.temporality != nil {
+		e.temporality.commit(updates.temporality)
+	}
+	if e.rates != nil {
+		e.rates.commit(updates.rates)
+	}
+	return err
+}
+
+// seriesUpdates collects the per-series state changes of one push. They are committed
+// after every payload was sent, so a batch retried after a failed send is converted
+// from the same state and produces the same rows.
+type seriesUpdates struct {
+	temporality temporalityUpdates
+	rates       rateUpdates
 }
 
 type metricBatch struct {
 	name   string
 	times  []int64
-	values []float64
+	values []interface{}
 	labels []map[string]interface{}
+
+	// fields holds the columns the exporter adds to each row (histogram fields, scope,
+	// start time, rate, ...), kept apart from the attributes in labels so neither can
+	// replace the other
+	fields []map[string]interface{}
+
+	// intValues holds the exact value of integer rows (nil for float rows)
+	intValues []interface{}
+	hasInts   bool
+
+	// hasValues is false while the batch only holds wide histogram/summary rows
+	hasValues bool
+
+	// nonFinite is the policy for NaN and ±Inf values (drop, null or keep)
+	nonFinite string
 }
 
-func (e *metricsExporter) batchToColumnar(metricName string, batch *metricBatch) ([]byte, error) {
-	// Dynamically extract all unique label keys from the batch
-	labelKeys := make(map[string]bool)
-	for _, labels := range batch.labels {
-		for key := range labels {
-			labelKeys[key] = true
+// add appends a float-valued row to the batch. NaN and ±Inf values are dropped,
+// written as null or kept according to the batch's policy.
+func (b *metricBatch) add(ts int64, value float64, labels, fields map[string]interface{}) {
+	if !isFinite(value) {
+		switch b.nonFinite {
+		case valuePolicyDrop:
+			return
+		case valuePolicyNull:
+			b.addNull(ts, labels, fields)
+			return
 		}
 	}
+	b.times = append(b.times, ts)
+	b.values = append(b.values, value)
+	b.intValues = append(b.intValues, nil)
+	b.hasValues = true
+	b.labels = append(b.labels, labels)
+	b.fields = append(b.fields, fields)
+}
 
-	// Create columns map with time and value
-	columns := map[string]interface{}{
-		"time":  batch.times,
-		"value": batch.values,
-	}
+// addNull appends a row with a null value
+func (b *metricBatch) addNull(ts int64, labels, fields map[string]interface{}) {
+	b.times = append(b.times, ts)
+	b.values = append(b.values, nil)
+	b.intValues = append(b.intValues, nil)
+	b.hasValues = true
+	b.labels = append(b.labels, labels)
+	b.fields = append(b.fields, fields)
+}
 
-	// For each unique label key, create a column with values
-	for labelKey := range labelKeys {
-		columnValues := make([]interface{}, len(batch.labels))
-		for i, labels := range batch.labels {
-			if val, ok := labels[labelKey]; ok {
-				columnValues[i] = val
-			} else {
-				// Use nil for missing values (Arc will handle nulls)
-				columnValues[i] = nil
-			}
+// addInt appends an integer-valued row to the batch. The value column keeps the
+// float64 approximation, the exact value is kept for the value_int column.
+func (b *metricBatch) addInt(ts int64, value int64, labels, fields map[string]interface{}) {
+	b.times = append(b.times, ts)
+	b.values = append(b.values, float64(value))
+	b.intValues = append(b.intValues, value)
+	b.hasInts = true
+	b.hasValues = true
+	b.labels = append(b.labels, labels)
+	b.fields = append(b.fields, fields)
+}
+
+// addWide appends a wide histogram or summary row. The row has no value; its values
+// (count, sum, bucket arrays, ...) are stored as columns next to the row's fields.
+func (b *metricBatch) addWide(ts int64, values, labels, fields map[string]interface{}) {
+	row := copyMap(fields)
+	for k, v := range values {
+		// A non-finite sum, min, max or percentile only nulls that column
+		if f, ok := v.(float64); ok && !isFinite(f) && b.nonFinite != valuePolicyKeep {
+			continue
 		}
-		columns[labelKey] = columnValues
+		row[k] = v
 	}
+	b.times = append(b.times, ts)
+	b.values = append(b.values, nil)
+	b.intValues = append(b.intValues, nil)
+	b.labels = append(b.labels, labels)
+	b.fields = append(b.fields, row)
+}
 
-	// Create columnar payload - Arc's columnar msgpack format with dynamic columns
-	columnarData := map[string]interface{}{
-		"m":       metricName,
-		"columns": columns,
+// addNumber appends a gauge or sum data point, keeping integer values exact
+func (b *metricBatch) addNumber(ts int64, dp pmetric.NumberDataPoint, labels, fields map[string]interface{}) {
+	if dp.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		b.addInt(ts, dp.IntValue(), labels, fields)
+		return
 	}
+	b.add(ts, getNumberValue(dp), labels, fields)
+}
 
-	// Serialize to msgpack
-	msgpackData, err := msgpack.Marshal(columnarData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal msgpack: %w", err)
+func (e *metricsExporter) batchToColumnar(metricName string, batch *metricBatch) ([]byte, error) {
+	// Create columns map with time and value (omitted if the batch only has wide rows)
+	columns := map[string]interface{}{
+		"time": batch.times,
 	}
-
-	// Compress with gzip
-	var buf bytes.Buffer
-	gzipWriter := gzip.NewWriter(&buf)
-	if _, err := gzipWriter.Write(msgpackData); err != nil {
-		return nil, fmt.Errorf("failed to compress: %w", err)
+	if batch.hasValues {
+		columns["value"] = batch.values
 	}
-	if err := gzipWriter.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+
+	// Exact integer values (counters, histogram counts) go to a separate column
+	if e.config.PreserveIntValues && batch.hasInts {
+		columns["value_int"] = batch.intValues
 	}
 
-	return buf.Bytes(), nil
+	// Fields added by the exporter always become columns; label columns are added
+	// according to the attributes mode
+	addFieldColumns(columns, batch.fields)
+	addAttributeColumns(e.config, columns, batch.labels, nil)
+
+	// Create columnar payload - Arc's columnar msgpack format with dynamic columns
+	return encodeColumnar(e.config, metricName, columns)
+}
+
+// addFieldColumns adds one column per field key, null in rows without that field
+func addFieldColumns(columns map[string]interface{}, rows []map[string]interface{}) {
+	for _, fields := range rows {
+		for key := range fields {
+			if _, ok := columns[key]; ok {
+				continue
+			}
+			columnValues := make([]interface{}, len(rows))
+			for i, row := range rows {
+				columnValues[i] = row[key]
+			}
+			columns[key] = columnValues
+		}
+	}
 }
 
-func (e *metricsExporter) processGauge(metric pmetric.Metric, batch *metricBatch, resourceAttrs map[string]interface{}) {
+func (e *metricsExporter) processGauge(metric pmetric.Metric, batch *metricBatch, resourceAttrs, metricFields map[string]interface{}) {
 	gauge := metric.Gauge()
 	for i := 0; i < gauge.DataPoints().Len(); i++ {
 		dp := gauge.DataPoints().At(i)
-		batch.times = append(batch.times, dp.Timestamp().AsTime().UnixMilli())
-		batch.values = append(batch.values, getNumberValue(dp))
 
 		// Merge resource attributes with data point attributes
 		labels := mergeAttributes(resourceAttrs, attributesToMap(dp.Attributes()))
-		batch.labels = append(batch.labels, labels)
+		fields := copyMap(metricFields)
+		e.addStartTime(fields, dp.StartTimestamp())
+
+		// Staleness markers and other points without a value
+		if e.skipNoRecordedValue(dp.Flags()) {
+			if e.config.NoRecordedValue == valuePolicyNull {
+				batch.addNull(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), labels, fields)
+			}
+			continue
+		}
+
+		batch.addNumber(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), dp, labels, fields)
 	}
 }
 
-func (e *metricsExporter) processSum(metric pmetric.Metric, batch *metricBatch, resourceAttrs map[string]interface{}) {
+// processSum writes sum data points. Per-second rates of cumulative counters are added
+// as a rate column or, if rates is not nil, written to the rates batch.
+func (e *metricsExporter) processSum(metric pmetric.Metric, batch, rates *metricBatch, updates *seriesUpdates, resourceAttrs, metricFields map[string]interface{}) {
 	sum := metric.Sum()
 	for i := 0; i < sum.DataPoints().Len(); i++ {
 		dp := sum.DataPoints().At(i)
-		batch.times = append(batch.times, dp.Timestamp().AsTime().UnixMilli())
-		batch.values = append(batch.values, getNumberValue(dp))
 
 		// Merge resource attributes with data point attributes
 		labels := mergeAttributes(resourceAttrs, attributesToMap(dp.Attributes()))
+		fields := copyMap(metricFields)
+
+		// When sums are converted, points that cannot be converted (no recorded value, NaN,
+		// ±Inf) get no start time: the source's start time does not describe their interval
+		converting := e.temporality.temporality(sum.AggregationTemporality()) != sum.AggregationTemporality()
+
+		// Staleness markers and other points without a value never reach the series state
+		if e.skipNoRecordedValue(dp.Flags()) {
+			if e.config.NoRecordedValue == valuePolicyNull {
+				if !converting {
+					e.addStartTime(fields, dp.StartTimestamp())
+				}
+				batch.addNull(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), labels, fields)
+			}
+			continue
+		}
+
+		// NaN and ±Inf never reach the series state; such points skip rates and conversion
+		// (a non-finite value stays non-finite once converted) and are dropped, nulled or
+		// kept by the batch
+		finite := isFinite(getNumberValue(dp))
+
+		// Series state is keyed on the labels and fields before any derived column is added
+		var key string
+		if e.rates != nil || e.temporality != nil {
+			key = seriesKey(metric.Name(), mergeAttributes(fields, labels))
+		}
+
+		// Rates are computed from the original cumulative points
+		if e.rates != nil && finite && isCumulativeCounter(sum) {
+			if rate, ok := e.rates.rate(key, dp, updates.rates); ok {
+				if rates != nil {
+					rates.add(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), rate, labels, copyMap(fields))
+				} else if isFinite(rate) || e.config.NonFiniteValues == valuePolicyKeep {
+					// Like wide histogram fields, a non-finite rate column is left null
+					fields["rate"] = rate
+				}
+			}
+		}
+
+		// Convert to the configured temporality
+		if converting && finite {
+			var ok bool
+			dp, ok = e.temporality.convertNumber(key, dp, sum.AggregationTemporality(), sum.IsMonotonic(), updates.temporality)
+			if !ok {
+				continue
+			}
+		}
+		if finite || !converting {
+			e.addStartTime(fields, dp.StartTimestamp())
+		}
 
 		// Only include internal metadata if explicitly requested
 		if e.config.IncludeMetricMetadata {
-			labels["_monotonic"] = sum.IsMonotonic()
-			labels["_aggregation_temporality"] = sum.AggregationTemporality().String()
+			fields["_monotonic"] = sum.IsMonotonic()
+			fields["_aggregation_temporality"] = e.temporality.temporality(sum.AggregationTemporality()).String()
 		}
 
-		batch.labels = append(batch.labels, labels)
+		batch.addNumber(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), dp, labels, fields)
 	}
 }
 
-func (e *metricsExporter) processHistogram(metric pmetric.Metric, batch *metricBatch, resourceAttrs map[string]interface{}) {
+func (e *metricsExporter) processHistogram(metric pmetric.Metric, batch *metricBatch, updates *seriesUpdates, resourceAttrs, metricFields map[string]interface{}) {
 	histogram := metric.Histogram()
 	for i := 0; i < histogram.DataPoints().Len(); i++ {
 		dp := histogram.DataPoints().At(i)
+		if e.skipNoRecordedValue(dp.Flags()) {
+			continue
+		}
 		attrs := mergeAttributes(resourceAttrs, attributesToMap(dp.Attributes()))
+		fields := copyMap(metricFields)
+
+		// Convert to the configured temporality
+		if e.temporality != nil {
+			var ok bool
+			dp, ok = e.temporality.convertHistogram(seriesKey(metric.Name(), mergeAttributes(fields, attrs)), dp, histogram.AggregationTemporality(), updates.temporality)
+			if !ok {
+				continue
+			}
+		}
+		e.addStartTime(fields, dp.StartTimestamp())
+
+		// Wide encoding: one row per data point with bucket arrays
+		if e.config.HistogramEncoding == histogramEncodingWide {
+			values := map[string]interface{}{
+				"count":         dp.Count(),
+				"sum":           dp.Sum(),
+				"bucket_bounds": dp.ExplicitBounds().AsRaw(),
+				"bucket_counts": dp.BucketCounts().AsRaw(),
+			}
+			if dp.HasMin() {
+				values["min"] = dp.Min()
+			}
+			if dp.HasMax() {
+				values["max"] = dp.Max()
+			}
+			// Precomputed percentiles become p50, p90, ... columns
+			for _, q := range e.config.HistogramQuantiles {
+				if value, ok := histogramQuantile(q, dp); ok {
+					values[quantileColumnName(q)] = value
+				}
+			}
+			batch.addWide(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), values, attrs, fields)
+			continue
+		}
 
-		// Store histogram as multiple data points with different labels
+		// Store histogram as multiple data points with different fields
 		// Count
-		countLabels := copyMap(attrs)
+		countFields := copyMap(fields)
 		if e.config.IncludeMetricMetadata {
-			countLabels["_histogram_field"] = "count"
+			countFields["_histogram_field"] = "count"
 		} else {
-			countLabels["histogram_field"] = "count"
+			countFields["histogram_field"] = "count"
 		}
-		batch.times = append(batch.times, dp.Timestamp().AsTime().UnixMilli())
-		batch.values = append(batch.values, float64(dp.Count()))
-		batch.labels = append(batch.labels, countLabels)
+		batch.addInt(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), int64(dp.Count()), attrs, countFields)
 
 		// Sum
-		sumLabels := copyMap(attrs)
+		sumFields := copyMap(fields)
 		if e.config.IncludeMetricMetadata {
-			sumLabels["_histogram_field"] = "sum"
+			sumFields["_histogram_field"] = "sum"
 		} else {
-			sumLabels["histogram_field"] = "sum"
+			sumFields["histogram_field"] = "sum"
 		}
-		batch.times = append(batch.times, dp.Timestamp().AsTime().UnixMilli())
-		batch.values = append(batch.values, dp.Sum())
-		batch.labels = append(batch.labels, sumLabels)
+		batch.add(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), dp.Sum(), attrs, sumFields)
 
 		// Min (if available)
 		if dp.HasMin() {
-			minLabels := copyMap(attrs)
+			minFields := copyMap(fields)
 			if e.config.IncludeMetricMetadata {
-				minLabels["_histogram_field"] = "min"
+				minFields["_histogram_field"] = "min"
 			} else {
-				minLabels["histogram_field"] = "min"
+				minFields["histogram_field"] = "min"
 			}
-			batch.times = append(batch.times, dp.Timestamp().AsTime().UnixMilli())
-			batch.values = append(batch.values, dp.Min())
-			batch.labels = append(batch.labels, minLabels)
+			batch.add(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), dp.Min(), attrs, minFields)
 		}
 
 		// Max (if available)
 		if dp.HasMax() {
-			maxLabels := copyMap(attrs)
+			maxFields := copyMap(fields)
 			if e.config.IncludeMetricMetadata {
-				maxLabels["_histogram_field"] = "max"
+				maxFields["_histogram_field"] = "max"
 			} else {
-				maxLabels["histogram_field"] = "max"
+				maxFields["histogram_field"] = "max"
 			}
-			batch.times = append(batch.times, dp.Timestamp().AsTime().UnixMilli())
-			batch.values = append(batch.values, dp.Max())
-			batch.labels = append(batch.labels, maxLabels)
+			batch.add(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), dp.Max(), attrs, maxFields)
 		}
 
 		// Buckets
 		for j := 0; j < dp.BucketCounts().Len(); j++ {
-			bucketLabels := copyMap(attrs)
+			bucketFields := copyMap(fields)
 			if e.config.IncludeMetricMetadata {
-				bucketLabels["_histogram_field"] = "bucket"
+				bucketFields["_histogram_field"] = "bucket"
 			} else {
-				bucketLabels["histogram_field"] = "bucket"
+				bucketFields["histogram_field"] = "bucket"
 			}
 			if j < dp.ExplicitBounds().Len() {
-				bucketLabels["le"] = dp.ExplicitBounds().At(j)
+				bucketFields["le"] = dp.ExplicitBounds().At(j)
+			} else {
+				bucketFields["le"] = "+Inf"
+			}
+
+			batch.addInt(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), int64(dp.BucketCounts().At(j)), attrs, bucketFields)
+		}
+
+		// Precomputed percentiles, laid out like summary quantiles
+		for _, q := range e.config.HistogramQuantiles {
+			value, ok := histogramQuantile(q, dp)
+			if !ok {
+				continue
+			}
+			quantileFields := copyMap(fields)
+			if e.config.IncludeMetricMetadata {
+				quantileFields["_histogram_field"] = "quantile"
 			} else {
-				bucketLabels["le"] = "+Inf"
+				quantileFields["histogram_field"] = "quantile"
+			}
+			quantileFields["quantile"] = q
+
+			batch.add(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), value, attrs, quantileFields)
+		}
+	}
+}
+
+func (e *metricsExporter) processExponentialHistogram(metric pmetric.Metric, batch *metricBatch, resourceAttrs, metricFields map[string]interface{}) {
+	histogram := metric.ExponentialHistogram()
+	fieldKey := "histogram_field"
+	if e.config.IncludeMetricMetadata {
+		fieldKey = "_histogram_field"
+	}
+
+	for i := 0; i < histogram.DataPoints().Len(); i++ {
+		dp := histogram.DataPoints().At(i)
+		if e.skipNoRecordedValue(dp.Flags()) {
+			continue
+		}
+		attrs := mergeAttributes(resourceAttrs, attributesToMap(dp.Attributes()))
+		fields := copyMap(metricFields)
+		e.addStartTime(fields, dp.StartTimestamp())
+		timestamp := toArcTime(dp.Timestamp(), e.config.TimestampPrecision)
+
+		// Wide encoding: one row per data point with the native bucket arrays
+		if e.config.HistogramEncoding == histogramEncodingWide {
+			values := map[string]interface{}{
+				"count":                  dp.Count(),
+				"scale":                  dp.Scale(),
+				"zero_count":             dp.ZeroCount(),
+				"positive_offset":        dp.Positive().Offset(),
+				"positive_bucket_counts": dp.Positive().BucketCounts().AsRaw(),
+				"negative_offset":        dp.Negative().Offset(),
+				"negative_bucket_counts": dp.Negative().BucketCounts().AsRaw(),
+			}
+			if dp.HasSum() {
+				values["sum"] = dp.Sum()
+			}
+			if dp.HasMin() {
+				values["min"] = dp.Min()
+			}
+			if dp.HasMax() {
+				values["max"] = dp.Max()
+			}
+			batch.addWide(timestamp, values, attrs, fields)
+			continue
+		}
+
+		// fieldsFor returns the row fields for the given histogram field with optional extra fields
+		fieldsFor := func(field string, extra map[string]interface{}) map[string]interface{} {
+			rowFields := copyMap(fields)
+			rowFields[fieldKey] = field
+			for k, v := range extra {
+				rowFields[k] = v
+			}
+			return rowFields
+		}
+
+		// Count, sum, min and max use the same layout as explicit histograms
+		batch.addInt(timestamp, int64(dp.Count()), attrs, fieldsFor("count", nil))
+		if dp.HasSum() {
+			batch.add(timestamp, dp.Sum(), attrs, fieldsFor("sum", nil))
+		}
+		if dp.HasMin() {
+			batch.add(timestamp, dp.Min(), attrs, fieldsFor("min", nil))
+		}
+		if dp.HasMax() {
+			batch.add(timestamp, dp.Max(), attrs, fieldsFor("max", nil))
+		}
+
+		if e.config.ExponentialHistogramMode == exponentialHistogramModeExplicit {
+			// Convert to explicit buckets, ordered from the most negative bound upwards
+			negative := dp.Negative()
+			for j := negative.BucketCounts().Len() - 1; j >= 0; j-- {
+				index := int(negative.Offset()) + j
+				batch.addInt(timestamp, int64(negative.BucketCounts().At(j)), attrs, fieldsFor("bucket", map[string]interface{}{
+					"le": -exponentialBucketLowerBound(dp.Scale(), index),
+				}))
+			}
+			batch.addInt(timestamp, int64(dp.ZeroCount()), attrs, fieldsFor("bucket", map[string]interface{}{"le": 0.0}))
+			positive := dp.Positive()
+			for j := 0; j < positive.BucketCounts().Len(); j++ {
+				index := int(positive.Offset()) + j
+				batch.addInt(timestamp, int64(positive.BucketCounts().At(j)), attrs, fieldsFor("bucket", map[string]interface{}{
+					"le": exponentialBucketLowerBound(dp.Scale(), index+1),
+				}))
 			}
+			continue
+		}
 
-			batch.times = append(batch.times, dp.Timestamp().AsTime().UnixMilli())
-			batch.values = append(batch.values, float64(dp.BucketCounts().At(j)))
-			batch.labels = append(batch.labels, bucketLabels)
+		// Native layout: zero count plus the sparse positive/negative buckets with scale and offset
+		batch.addInt(timestamp, int64(dp.ZeroCount()), attrs, fieldsFor("zero_count", nil))
+		for _, side := range []struct {
+			field   string
+			buckets pmetric.ExponentialHistogramDataPointBuckets
+		}{
+			{field: "positive_bucket", buckets: dp.Positive()},
+			{field: "negative_bucket", buckets: dp.Negative()},
+		} {
+			for j := 0; j < side.buckets.BucketCounts().Len(); j++ {
+				batch.addInt(timestamp, int64(side.buckets.BucketCounts().At(j)), attrs, fieldsFor(side.field, map[string]interface{}{
+					"scale":        dp.Scale(),
+					"offset":       side.buckets.Offset(),
+					"bucket_index": int64(side.buckets.Offset()) + int64(j),
+				}))
+			}
 		}
 	}
 }
 
-func (e *metricsExporter) processSummary(metric pmetric.Metric, batch *metricBatch, resourceAttrs map[string]interface{}) {
+// exponentialBucketLowerBound returns the lower boundary of the bucket at index for the
+// given scale, i.e. base^index where base = 2^(2^-scale)
+func exponentialBucketLowerBound(scale int32, index int) float64 {
+	return math.Exp2(float64(index) * math.Exp2(-float64(scale)))
+}
+
+func (e *metricsExporter) processSummary(metric pmetric.Metric, batch *metricBatch, resourceAttrs, metricFields map[string]interface{}) {
 	summary := metric.Summary()
 	for i := 0; i < summary.DataPoints().Len(); i++ {
 		dp := summary.DataPoints().At(i)
+		if e.skipNoRecordedValue(dp.Flags()) {
+			continue
+		}
 		attrs := mergeAttributes(resourceAttrs, attributesToMap(dp.Attributes()))
+		fields := copyMap(metricFields)
+		e.addStartTime(fields, dp.StartTimestamp())
+
+		// Wide encoding: one row per data point with quantile arrays
+		if e.config.HistogramEncoding == histogramEncodingWide {
+			quantiles := make([]float64, 0, dp.QuantileValues().Len())
+			quantileValues := make([]float64, 0, dp.QuantileValues().Len())
+			for j := 0; j < dp.QuantileValues().Len(); j++ {
+				quantiles = append(quantiles, dp.QuantileValues().At(j).Quantile())
+				quantileValues = append(quantileValues, dp.QuantileValues().At(j).Value())
+			}
+			values := map[string]interface{}{
+				"count":           dp.Count(),
+				"sum":             dp.Sum(),
+				"quantiles":       quantiles,
+				"quantile_values": quantileValues,
+			}
+			batch.addWide(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), values, attrs, fields)
+			continue
+		}
 
 		// Count
-		countLabels := copyMap(attrs)
+		countFields := copyMap(fields)
 		if e.config.IncludeMetricMetadata {
-			countLabels["_summary_field"] = "count"
+			countFields["_summary_field"] = "count"
 		} else {
-			countLabels["summary_field"] = "count"
+			countFields["summary_field"] = "count"
 		}
-		batch.times = append(batch.times, dp.Timestamp().AsTime().UnixMilli())
-		batch.values = append(batch.values, float64(dp.Count()))
-		batch.labels = append(batch.labels, countLabels)
+		batch.addInt(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), int64(dp.Count()), attrs, countFields)
 
 		// Sum
-		sumLabels := copyMap(attrs)
+		sumFields := copyMap(fields)
 		if e.config.IncludeMetricMetadata {
-			sumLabels["_summary_field"] = "sum"
+			sumFields["_summary_field"] = "sum"
 		} else {
-			sumLabels["summary_field"] = "sum"
+			sumFields["summary_field"] = "sum"
 		}
-		batch.times = append(batch.times, dp.Timestamp().AsTime().UnixMilli())
-		batch.values = append(batch.values, dp.Sum())
-		batch.labels = append(batch.labels, sumLabels)
+		batch.add(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), dp.Sum(), attrs, sumFields)
 
 		// Quantiles
 		for j := 0; j < dp.QuantileValues().Len(); j++ {
 			qv := dp.QuantileValues().At(j)
-			quantileLabels := copyMap(attrs)
+			quantileFields := copyMap(fields)
 			if e.config.IncludeMetricMetadata {
-				quantileLabels["_summary_field"] = "quantile"
+				quantileFields["_summary_field"] = "quantile"
+			} else {
+				quantileFields["summary_field"] = "quantile"
+			}
+			quantileFields["quantile"] = qv.Quantile()
+
+			batch.add(toArcTime(dp.Timestamp(), e.config.TimestampPrecision), qv.Value(), attrs, quantileFields)
+		}
+	}
+}
+
+// processExemplars writes one row per exemplar with the metric name, the exemplar value
+// and its trace and span IDs. Rows carry the resource, scope and data point attributes
+// plus the exemplar's filtered attributes.
+func (e *metricsExporter) processExemplars(metric pmetric.Metric, batch *metricBatch, resourceAttrs, scopeFields map[string]interface{}) {
+	add := func(dpAttrs pcommon.Map, exemplars pmetric.ExemplarSlice) {
+		if exemplars.Len() == 0 {
+			return
+		}
+		attrs := mergeAttributes(resourceAttrs, attributesToMap(dpAttrs))
+		for i := 0; i < exemplars.Len(); i++ {
+			exemplar := exemplars.At(i)
+
+			labels := mergeAttributes(attrs, attributesToMap(exemplar.FilteredAttributes()))
+			fields := copyMap(scopeFields)
+			fields["metric_name"] = metric.Name()
+			fields["trace_id"] = exemplar.TraceID().String()
+			fields["span_id"] = exemplar.SpanID().String()
+
+			ts := toArcTime(exemplar.Timestamp(), e.config.TimestampPrecision)
+			if exemplar.ValueType() == pmetric.ExemplarValueTypeInt {
+				batch.addInt(ts, exemplar.IntValue(), labels, fields)
 			} else {
-				quantileLabels["summary_field"] = "quantile"
+				batch.add(ts, exemplar.DoubleValue(), labels, fields)
 			}
-			quantileLabels["quantile"] = qv.Quantile()
+		}
+	}
 
-			batch.times = append(batch.times, dp.Timestamp().AsTime().UnixMilli())
-			batch.values = append(batch.values, qv.Value())
-			batch.labels = append(batch.labels, quantileLabels)
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		for i := 0; i < metric.Gauge().DataPoints().Len(); i++ {
+			dp := metric.Gauge().DataPoints().At(i)
+			add(dp.Attributes(), dp.Exemplars())
+		}
+	case pmetric.MetricTypeSum:
+		for i := 0; i < metric.Sum().DataPoints().Len(); i++ {
+			dp := metric.Sum().DataPoints().At(i)
+			add(dp.Attributes(), dp.Exemplars())
 		}
+	case pmetric.MetricTypeHistogram:
+		for i := 0; i < metric.Histogram().DataPoints().Len(); i++ {
+			dp := metric.Histogram().DataPoints().At(i)
+			add(dp.Attributes(), dp.Exemplars())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		for i := 0; i < metric.ExponentialHistogram().DataPoints().Len(); i++ {
+			dp := metric.ExponentialHistogram().DataPoints().At(i)
+			add(dp.Attributes(), dp.Exemplars())
+		}
+	}
+}
+
+// sendCatalog writes metrics not described in the database's catalog within the interval
+func (e *metricsExporter) sendCatalog(ctx context.Context, database string, md pmetric.Metrics) error {
+	pending := e.catalog.pending(database, md)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	payload, err := catalogToColumnar(e.config, pending)
+	if err != nil {
+		return fmt.Errorf("failed to convert metrics catalog: %w", err)
+	}
+	if err := e.sendToArc(ctx, database, payload); err != nil {
+		return fmt.Errorf("failed to send metrics catalog: %w", err)
 	}
+
+	e.catalog.markSent(pending)
+	return nil
 }
 
-func (e *metricsExporter) sendToArc(ctx context.Context, payload []byte) error {
+func (e *metricsExporter) sendToArc(ctx context.Context, database string, payload []byte) error {
 	url := fmt.Sprintf("%s/api/v1/write/msgpack", e.config.Endpoint)
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
@@ -318,8 +871,11 @@ func (e *metricsExporter) sendToArc(ctx context.Context, payload []byte) error {
 	}
 
 	req.Header.Set("Content-Type", "application/msgpack")
-	req.Header.Set("Content-Encoding", "gzip")
-	req.Header.Set("X-Arc-Database", e.config.MetricsDatabase)
+	if encoding := contentEncoding(e.config.Compression); encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	req.Header.Set("X-Arc-Timestamp-Precision", e.config.TimestampPrecision)
+	req.Header.Set("X-Arc-Database", database)
 
 	if e.config.AuthToken != "" {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.config.AuthToken))
@@ -331,9 +887,8 @@ func (e *metricsExporter) sendToArc(ctx context.Context, payload []byte) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("arc returned status %d: %s", resp.StatusCode, string(body))
+	if err := checkArcResponse(resp, e.logger); err != nil {
+		return err
 	}
 
 	e.logger.Debug("Successfully sent metrics to Arc",
@@ -354,6 +909,103 @@ func getNumberValue(dp pmetric.NumberDataPoint) float64 {
 	}
 }
 
+// isFinite reports whether a value is neither NaN nor ±Inf
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// skipNoRecordedValue reports whether a data point flagged NoRecordedValue is left out of
+// the regular rows. Gauges and sums may still write a null row for it.
+func (e *metricsExporter) skipNoRecordedValue(flags pmetric.DataPointFlags) bool {
+	return flags.NoRecordedValue() && e.config.NoRecordedValue != valuePolicyKeep
+}
+
+// addStartTime adds the data point's start time as a start_time column when metadata
+// columns are enabled. Points without a start time (most gauges) leave it null.
+func (e *metricsExporter) addStartTime(fields map[string]interface{}, start pcommon.Timestamp) {
+	if e.config.MetricMetadataColumns && start != 0 {
+		fields["start_time"] = toArcTime(start, e.config.TimestampPrecision)
+	}
+}
+
+// isCumulativeCounter reports whether a sum is a monotonic cumulative counter
+func isCumulativeCounter(sum pmetric.Sum) bool {
+	return sum.IsMonotonic() && sum.AggregationTemporality() == pmetric.AggregationTemporalityCumulative
+}
+
+// histogramQuantile estimates the q-quantile of an explicit-bucket histogram by linear
+// interpolation within the bucket holding the target rank. The first bucket starts at the
+// recorded min (or 0) and the +Inf bucket ends at the recorded max (or the highest bound).
+// It returns false if the histogram is empty or has no bounds.
+func histogramQuantile(q float64, dp pmetric.HistogramDataPoint) (float64, bool) {
+	bounds := dp.ExplicitBounds()
+	counts := dp.BucketCounts()
+	if bounds.Len() == 0 || counts.Len() != bounds.Len()+1 {
+		return 0, false
+	}
+
+	var total uint64
+	for i := 0; i < counts.Len(); i++ {
+		total += counts.At(i)
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	rank := q * float64(total)
+	var cumulative uint64
+	for i := 0; i < counts.Len(); i++ {
+		count := counts.At(i)
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+
+		// Bucket boundaries
+		var lower, upper float64
+		switch {
+		case i == 0:
+			upper = bounds.At(0)
+			lower = math.Min(0, upper)
+			if dp.HasMin() {
+				lower = dp.Min()
+			}
+		case i == bounds.Len():
+			lower = bounds.At(i - 1)
+			upper = lower
+			if dp.HasMax() {
+				upper = dp.Max()
+			}
+		default:
+			lower = bounds.At(i - 1)
+			upper = bounds.At(i)
+		}
+
+		return lower + (upper-lower)*(rank-float64(cumulative))/float64(count), true
+	}
+
+	return bounds.At(bounds.Len() - 1), true
+}
+
+// quantileColumnName returns the column name for a precomputed percentile,
+// e.g. 0.5 -> "p50", 0.999 -> "p99_9"
+func quantileColumnName(q float64) string {
+	percentile := strconv.FormatFloat(math.Round(q*1e6)/1e4, 'f', -1, 64)
+	return "p" + strings.ReplaceAll(percentile, ".", "_")
+}
+
+// measurementForMetric returns the measurement for a metric name. The first matching
+// metric_measurements rule wins and reports the measurement as shared; otherwise each
+// metric gets its own table named after the sanitized metric name.
+func (e *metricsExporter) measurementForMetric(name string) (string, bool) {
+	for _, rule := range e.measurementRules {
+		if rule.pattern.MatchString(name) {
+			return rule.measurement, true
+		}
+	}
+	return sanitizeMetricName(name), false
+}
+
 // sanitizeMetricName converts OTel metric names to Arc-friendly table names
 // e.g., "system.cpu.usage" -> "system_cpu_usage"
 func sanitizeMetricName(name string) string {
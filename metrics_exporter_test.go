@@ -0,0 +1,523 @@
+package arcexporter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// newTestConfig returns a validated default config after applying modify
+func newTestConfig(t *testing.T, modify func(cfg *Config)) *Config {
+	t.Helper()
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = "http://localhost:8000"
+	if modify != nil {
+		modify(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid config: %v", err)
+	}
+	return cfg
+}
+
+func TestExponentialBucketLowerBound(t *testing.T) {
+	tests := []struct {
+		name  string
+		scale int32
+		index int
+		want  float64
+	}{
+		{name: "scale 0 index 0", scale: 0, index: 0, want: 1},
+		{name: "scale 0 index 3", scale: 0, index: 3, want: 8},
+		{name: "scale 0 negative index", scale: 0, index: -2, want: 0.25},
+		{name: "scale 1 index 1", scale: 1, index: 1, want: math.Sqrt2},
+		{name: "scale 1 index 4", scale: 1, index: 4, want: 4},
+		{name: "scale -1 index 2", scale: -1, index: 2, want: 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exponentialBucketLowerBound(tt.scale, tt.index)
+			if math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("exponentialBucketLowerBound(%d, %d) = %v, want %v", tt.scale, tt.index, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessExponentialHistogramExplicit(t *testing.T) {
+	cfg := newTestConfig(t, func(cfg *Config) {
+		cfg.ExponentialHistogramMode = exponentialHistogramModeExplicit
+	})
+	exp := &metricsExporter{config: cfg}
+
+	metric := pmetric.NewMetric()
+	metric.SetName("latency")
+	dp := metric.SetEmptyExponentialHistogram().DataPoints().AppendEmpty()
+	dp.SetScale(0)
+	dp.SetCount(10)
+	dp.SetZeroCount(1)
+	// Negative buckets (-2, -1] and (-4, -2]
+	dp.Negative().SetOffset(0)
+	dp.Negative().BucketCounts().FromRaw([]uint64{2, 3})
+	// Positive buckets (2, 4] and (4, 8]
+	dp.Positive().SetOffset(1)
+	dp.Positive().BucketCounts().FromRaw([]uint64{1, 3})
+
+	batch := &metricBatch{}
+	exp.processExponentialHistogram(metric, batch, map[string]interface{}{}, map[string]interface{}{})
+
+	type bucket struct {
+		le    float64
+		count float64
+	}
+	var got []bucket
+	for i, fields := range batch.fields {
+		if fields["histogram_field"] != "bucket" {
+			continue
+		}
+		got = append(got, bucket{le: fields["le"].(float64), count: batch.values[i].(float64)})
+	}
+
+	want := []bucket{
+		{le: -2, count: 3},
+		{le: -1, count: 2},
+		{le: 0, count: 1},
+		{le: 4, count: 1},
+		{le: 8, count: 3},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d buckets %v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("bucket %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	// Most cases use buckets (-inf, 10], (10, 20], (20, 50], (50, +inf) with 10 points each
+	newDataPoint := func(bounds []float64, counts []uint64) pmetric.HistogramDataPoint {
+		dp := pmetric.NewHistogramDataPoint()
+		dp.ExplicitBounds().FromRaw(bounds)
+		dp.BucketCounts().FromRaw(counts)
+		return dp
+	}
+
+	tests := []struct {
+		name   string
+		dp     func() pmetric.HistogramDataPoint
+		q      float64
+		want   float64
+		wantOK bool
+	}{
+		{
+			name: "q=0 starts at zero",
+			dp: func() pmetric.HistogramDataPoint {
+				return newDataPoint([]float64{10, 20, 50}, []uint64{10, 10, 10, 10})
+			},
+			q:      0,
+			want:   0,
+			wantOK: true,
+		},
+		{
+			name: "first bucket starts at min",
+			dp: func() pmetric.HistogramDataPoint {
+				dp := newDataPoint([]float64{10, 20, 50}, []uint64{10, 10, 10, 10})
+				dp.SetMin(2)
+				return dp
+			},
+			q:      0.125,
+			want:   6,
+			wantOK: true,
+		},
+		{
+			name:   "first bucket with negative bound",
+			dp:     func() pmetric.HistogramDataPoint { return newDataPoint([]float64{-10, 0}, []uint64{4, 4, 0}) },
+			q:      0.25,
+			want:   -10,
+			wantOK: true,
+		},
+		{
+			name: "interpolates within a bucket",
+			dp: func() pmetric.HistogramDataPoint {
+				return newDataPoint([]float64{10, 20, 50}, []uint64{10, 10, 10, 10})
+			},
+			q:      0.375,
+			want:   15,
+			wantOK: true,
+		},
+		{
+			name: "median on a bucket boundary",
+			dp: func() pmetric.HistogramDataPoint {
+				return newDataPoint([]float64{10, 20, 50}, []uint64{10, 10, 10, 10})
+			},
+			q:      0.5,
+			want:   20,
+			wantOK: true,
+		},
+		{
+			name: "+Inf bucket without max uses the highest bound",
+			dp: func() pmetric.HistogramDataPoint {
+				return newDataPoint([]float64{10, 20, 50}, []uint64{10, 10, 10, 10})
+			},
+			q:      0.9,
+			want:   50,
+			wantOK: true,
+		},
+		{
+			name: "+Inf bucket ends at max",
+			dp: func() pmetric.HistogramDataPoint {
+				dp := newDataPoint([]float64{10, 20, 50}, []uint64{10, 10, 10, 10})
+				dp.SetMax(80)
+				return dp
+			},
+			q:      0.9,
+			want:   68,
+			wantOK: true,
+		},
+		{
+			name: "q=1 is max",
+			dp: func() pmetric.HistogramDataPoint {
+				dp := newDataPoint([]float64{10, 20, 50}, []uint64{10, 10, 10, 10})
+				dp.SetMax(80)
+				return dp
+			},
+			q:      1,
+			want:   80,
+			wantOK: true,
+		},
+		{
+			name:   "empty buckets are skipped",
+			dp:     func() pmetric.HistogramDataPoint { return newDataPoint([]float64{10, 20, 50}, []uint64{0, 0, 4, 0}) },
+			q:      0.5,
+			want:   35,
+			wantOK: true,
+		},
+		{
+			name:   "empty histogram",
+			dp:     func() pmetric.HistogramDataPoint { return newDataPoint([]float64{10, 20}, []uint64{0, 0, 0}) },
+			q:      0.5,
+			wantOK: false,
+		},
+		{
+			name:   "no bounds",
+			dp:     func() pmetric.HistogramDataPoint { return newDataPoint(nil, []uint64{5}) },
+			q:      0.5,
+			wantOK: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := histogramQuantile(tt.q, tt.dp())
+			if ok != tt.wantOK {
+				t.Fatalf("histogramQuantile(%v) ok = %v, want %v", tt.q, ok, tt.wantOK)
+			}
+			if ok && math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("histogramQuantile(%v) = %v, want %v", tt.q, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuantileColumnName(t *testing.T) {
+	tests := map[float64]string{
+		0.5:   "p50",
+		0.9:   "p90",
+		0.99:  "p99",
+		0.999: "p99_9",
+		1:     "p100",
+	}
+	for q, want := range tests {
+		if got := quantileColumnName(q); got != want {
+			t.Errorf("quantileColumnName(%v) = %q, want %q", q, got, want)
+		}
+	}
+}
+
+func TestWideHistogramPercentileColumnsInJSONMode(t *testing.T) {
+	cfg := newTestConfig(t, func(cfg *Config) {
+		cfg.Compression = "none"
+		cfg.AttributesMode = attributesModeJSON
+		cfg.HistogramEncoding = histogramEncodingWide
+		cfg.HistogramQuantiles = []float64{0.5}
+	})
+	exp, err := newMetricsExporter(cfg, exportertest.NewNopCreateSettings())
+	if err != nil {
+		t.Fatalf("newMetricsExporter: %v", err)
+	}
+
+	metric := pmetric.NewMetric()
+	metric.SetName("latency")
+	dp := metric.SetEmptyHistogram().DataPoints().AppendEmpty()
+	dp.Attributes().PutStr("a", "b")
+	dp.ExplicitBounds().FromRaw([]float64{1, 2})
+	dp.BucketCounts().FromRaw([]uint64{1, 2, 1})
+	dp.SetCount(4)
+
+	batch := &metricBatch{}
+	exp.processHistogram(metric, batch, &seriesUpdates{}, map[string]interface{}{}, map[string]interface{}{})
+	payload, err := exp.batchToColumnar("latency", batch)
+	if err != nil {
+		t.Fatalf("batchToColumnar: %v", err)
+	}
+
+	var decoded struct {
+		Columns map[string][]interface{} `msgpack:"columns"`
+	}
+	if err := msgpack.Unmarshal(payload, &decoded); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if _, ok := decoded.Columns["p50"]; !ok {
+		t.Errorf("missing p50 column, got columns %v", decoded.Columns)
+	}
+	if got := decoded.Columns[attributesColumn]; len(got) != 1 || got[0] != `{"a":"b"}` {
+		t.Errorf("attributes = %v, want only the data point attributes", got)
+	}
+}
+
+func TestAttributesDoNotReplaceExporterColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		promoted []string
+	}{
+		{name: "columns", mode: attributesModeColumns},
+		{name: "json", mode: attributesModeJSON},
+		{name: "hybrid", mode: attributesModeHybrid, promoted: []string{"count", "scope_name"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, func(cfg *Config) {
+				cfg.Compression = "none"
+				cfg.AttributesMode = tt.mode
+				cfg.PromotedAttributes = tt.promoted
+				cfg.HistogramEncoding = histogramEncodingWide
+			})
+			exp, err := newMetricsExporter(cfg, exportertest.NewNopCreateSettings())
+			if err != nil {
+				t.Fatalf("newMetricsExporter: %v", err)
+			}
+
+			metric := pmetric.NewMetric()
+			metric.SetName("latency")
+			dp := metric.SetEmptyHistogram().DataPoints().AppendEmpty()
+			dp.Attributes().PutStr("count", "user")
+			dp.Attributes().PutStr("scope_name", "user")
+			dp.ExplicitBounds().FromRaw([]float64{1})
+			dp.BucketCounts().FromRaw([]uint64{1, 3})
+			dp.SetCount(4)
+
+			batch := &metricBatch{}
+			exp.processHistogram(metric, batch, &seriesUpdates{}, map[string]interface{}{}, map[string]interface{}{"scope_name": "lib"})
+			payload, err := exp.batchToColumnar("latency", batch)
+			if err != nil {
+				t.Fatalf("batchToColumnar: %v", err)
+			}
+
+			var decoded struct {
+				Columns map[string][]interface{} `msgpack:"columns"`
+			}
+			if err := msgpack.Unmarshal(payload, &decoded); err != nil {
+				t.Fatalf("decode payload: %v", err)
+			}
+			if got := decoded.Columns["count"]; len(got) != 1 || fmt.Sprint(got[0]) != "4" {
+				t.Errorf("count = %v, want the histogram count", got)
+			}
+			if got := decoded.Columns["scope_name"]; len(got) != 1 || got[0] != "lib" {
+				t.Errorf("scope_name = %v, want the instrumentation scope", got)
+			}
+			if got := decoded.Columns[attributesColumn]; len(got) != 1 || got[0] != `{"count":"user","scope_name":"user"}` {
+				t.Errorf("attributes = %v, want the colliding data point attributes", got)
+			}
+		})
+	}
+}
+
+func TestProcessSumNonFiniteSkipsSeriesState(t *testing.T) {
+	cfg := newTestConfig(t, func(cfg *Config) {
+		cfg.AggregationTemporality = aggregationTemporalityDelta
+		cfg.CounterRate = counterRateColumn
+		cfg.QueueSettings.NumConsumers = 1
+	})
+	exp, err := newMetricsExporter(cfg, exportertest.NewNopCreateSettings())
+	if err != nil {
+		t.Fatalf("newMetricsExporter: %v", err)
+	}
+
+	metric := pmetric.NewMetric()
+	metric.SetName("requests")
+	sum := metric.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	for i, v := range []float64{5, math.NaN(), 15} {
+		dp := sum.DataPoints().AppendEmpty()
+		dp.SetStartTimestamp(pcommon.Timestamp(time.Second))
+		dp.SetTimestamp(pcommon.Timestamp(int64(i+1) * 10 * int64(time.Second)))
+		dp.SetDoubleValue(v)
+	}
+
+	batch := &metricBatch{nonFinite: cfg.NonFiniteValues}
+	updates := &seriesUpdates{temporality: make(temporalityUpdates), rates: make(rateUpdates)}
+	exp.processSum(metric, batch, nil, updates, map[string]interface{}{}, map[string]interface{}{})
+
+	// The first point is the baseline and the NaN point is dropped
+	if len(batch.values) != 1 {
+		t.Fatalf("got %d values %v, want 1", len(batch.values), batch.values)
+	}
+	if got := batch.values[0].(float64); got != 10 {
+		t.Errorf("delta = %v, want 10", got)
+	}
+	if got := batch.fields[0]["rate"]; got != 0.5 {
+		t.Errorf("rate = %v, want 0.5", got)
+	}
+}
+
+func TestProcessHistogramNonFiniteSumIsConverted(t *testing.T) {
+	for _, policy := range []string{valuePolicyDrop, valuePolicyKeep} {
+		t.Run(policy, func(t *testing.T) {
+			cfg := newTestConfig(t, func(cfg *Config) {
+				cfg.AggregationTemporality = aggregationTemporalityDelta
+				cfg.NonFiniteValues = policy
+				cfg.QueueSettings.NumConsumers = 1
+			})
+			exp, err := newMetricsExporter(cfg, exportertest.NewNopCreateSettings())
+			if err != nil {
+				t.Fatalf("newMetricsExporter: %v", err)
+			}
+
+			metric := pmetric.NewMetric()
+			metric.SetName("latency")
+			histogram := metric.SetEmptyHistogram()
+			histogram.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+			for i, sum := range []float64{30, math.NaN()} {
+				dp := histogram.DataPoints().AppendEmpty()
+				dp.SetStartTimestamp(pcommon.Timestamp(time.Second))
+				dp.SetTimestamp(pcommon.Timestamp(int64(i+1) * 10 * int64(time.Second)))
+				dp.ExplicitBounds().FromRaw([]float64{10})
+				dp.BucketCounts().FromRaw([]uint64{uint64(2 + 3*i), 0})
+				dp.SetCount(uint64(2 + 3*i))
+				dp.SetSum(sum)
+			}
+
+			batch := &metricBatch{nonFinite: cfg.NonFiniteValues}
+			exp.processHistogram(metric, batch, &seriesUpdates{temporality: make(temporalityUpdates)}, map[string]interface{}{}, map[string]interface{}{})
+
+			// Count and buckets of the second point are deltas; the sum follows the policy
+			got := map[string]float64{}
+			var buckets []float64
+			for i, fields := range batch.fields {
+				field := fields["histogram_field"].(string)
+				got[field] = batch.values[i].(float64)
+				if field == "bucket" {
+					buckets = append(buckets, got[field])
+				}
+			}
+			if got["count"] != 3 || len(buckets) != 2 || buckets[0] != 3 || buckets[1] != 0 {
+				t.Errorf("count, buckets = %v, %v, want 3, [3 0]", got["count"], buckets)
+			}
+			if sum, ok := got["sum"]; ok != (policy == valuePolicyKeep) || ok && !math.IsNaN(sum) {
+				t.Errorf("sum = %v (written %v), want NaN written only with keep", sum, ok)
+			}
+		})
+	}
+}
+
+func TestMeasurementForMetric(t *testing.T) {
+	// Rules are compiled by the exporter, not by Validate
+	cfg := createDefaultConfig().(*Config)
+	cfg.MetricMeasurements = []MetricMeasurementRule{
+		{Match: `^system\.`, Measurement: "system.metrics"},
+		{Match: `^http\.`, Measurement: "http"},
+	}
+	exp, err := newMetricsExporter(cfg, exportertest.NewNopCreateSettings())
+	if err != nil {
+		t.Fatalf("newMetricsExporter: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		want       string
+		wantShared bool
+	}{
+		{name: "system.cpu.usage", want: "system_metrics", wantShared: true},
+		{name: "http.server.duration", want: "http", wantShared: true},
+		{name: "process.memory.usage", want: "process_memory_usage"},
+	}
+	for _, tt := range tests {
+		got, shared := exp.measurementForMetric(tt.name)
+		if got != tt.want || shared != tt.wantShared {
+			t.Errorf("measurementForMetric(%q) = %q, %v, want %q, %v", tt.name, got, shared, tt.want, tt.wantShared)
+		}
+	}
+
+	cfg.MetricMeasurements = []MetricMeasurementRule{{Match: "(", Measurement: "broken"}}
+	if _, err := newMetricsExporter(cfg, exportertest.NewNopCreateSettings()); err == nil {
+		t.Error("newMetricsExporter() succeeded with an invalid rule, want error")
+	}
+}
+
+func TestPushMetricsSendsRemainingGroupsAfterRejection(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Measurement string `msgpack:"m"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := msgpack.Unmarshal(body, &payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		if payload.Measurement == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received[payload.Measurement]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, func(cfg *Config) {
+		cfg.Endpoint = server.URL
+		cfg.Compression = "none"
+	})
+	exp, err := newMetricsExporter(cfg, exportertest.NewNopCreateSettings())
+	if err != nil {
+		t.Fatalf("newMetricsExporter: %v", err)
+	}
+	exp.client = server.Client()
+
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	for _, name := range []string{"bad", "good_a", "good_b", "good_c"} {
+		metric := metrics.AppendEmpty()
+		metric.SetName(name)
+		metric.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(1)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := exp.pushMetrics(context.Background(), md); !consumererror.IsPermanent(err) {
+			t.Fatalf("pushMetrics() = %v, want a permanent error", err)
+		}
+	}
+	for _, name := range []string{"good_a", "good_b", "good_c"} {
+		if received[name] != 5 {
+			t.Errorf("%s received %d payloads, want 5", name, received[name])
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package arcexporter
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// rateState is the last point of a cumulative counter series
+type rateState struct {
+	start    pcommon.Timestamp
+	time     pcommon.Timestamp
+	value    float64
+	lastSeen time.Time
+}
+
+// rateTracker computes per-second rates of monotonic cumulative counters from
+// consecutive points of each series. Series not seen within the TTL are forgotten.
+type rateTracker struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	series map[string]*rateState
+}
+
+func newRateTracker(ttl time.Duration) *rateTracker {
+	return &rateTracker{
+		ttl:    ttl,
+		series: make(map[string]*rateState),
+	}
+}
+
+// rateUpdates holds the series states changed while computing the rates of one batch.
+// They are committed once the batch was sent, so a retried batch gets the same rates.
+type rateUpdates map[string]*rateState
+
+// lookup returns the state of a series as seen by the current batch: its pending update
+// or a copy of the committed state
+func (t *rateTracker) lookup(key string, updates rateUpdates) (*rateState, bool) {
+	if state, ok := updates[key]; ok {
+		return state, true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	committed, ok := t.series[key]
+	if !ok {
+		return nil, false
+	}
+	state := *committed
+	return &state, true
+}
+
+// commit applies the series states of a batch that was sent successfully
+func (t *rateTracker) commit(updates rateUpdates) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, state := range updates {
+		t.series[key] = state
+	}
+}
+
+// prune drops series not updated within the TTL
+func (t *rateTracker) prune() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for key, state := range t.series {
+		if now.Sub(state.lastSeen) >= t.ttl {
+			delete(t.series, key)
+		}
+	}
+}
+
+// rate returns the per-second rate of a counter since the previous point of its series.
+// After a reset (new StartTimestamp or decreasing value) the rate is computed since the
+// new StartTimestamp if it is known. It returns false for the first point of a series,
+// for duplicate or out-of-order points, and for resets without a usable start time.
+// The new series state is recorded in updates and only takes effect once committed.
+func (t *rateTracker) rate(key string, dp pmetric.NumberDataPoint, updates rateUpdates) (float64, bool) {
+	value := getNumberValue(dp)
+	state, ok := t.lookup(key, updates)
+	if !ok {
+		updates[key] = &rateState{
+			start:    dp.StartTimestamp(),
+			time:     dp.Timestamp(),
+			value:    value,
+			lastSeen: time.Now(),
+		}
+		return 0, false
+	}
+	if dp.Timestamp() <= state.time {
+		return 0, false
+	}
+
+	prevTime, prevValue := state.time, state.value
+	reset := dp.StartTimestamp() != state.start || value < prevValue
+	updates[key] = state
+
+	state.start = dp.StartTimestamp()
+	state.time = dp.Timestamp()
+	state.value = value
+	state.lastSeen = time.Now()
+
+	if reset {
+		// The counter restarted from zero at its (new) start time
+		if dp.StartTimestamp() == 0 || dp.StartTimestamp() < prevTime || dp.StartTimestamp() >= dp.Timestamp() {
+			return 0, false
+		}
+		prevTime, prevValue = dp.StartTimestamp(), 0
+	}
+
+	seconds := float64(dp.Timestamp()-prevTime) / float64(time.Second)
+	return (value - prevValue) / seconds, true
+}
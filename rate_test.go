@@ -0,0 +1,101 @@
+package arcexporter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestRateTrackerRate(t *testing.T) {
+	// Points are (start, time, value) with times in seconds
+	type point struct {
+		start, time int64
+		value       float64
+	}
+	type result struct {
+		rate float64
+		ok   bool
+	}
+
+	tests := []struct {
+		name   string
+		points []point
+		want   []result
+	}{
+		{
+			name:   "steady counter",
+			points: []point{{1, 10, 100}, {1, 20, 150}, {1, 30, 200}},
+			want:   []result{{0, false}, {5, true}, {5, true}},
+		},
+		{
+			name:   "reset with new start time restarts from zero",
+			points: []point{{1, 10, 100}, {1, 20, 150}, {25, 30, 40}},
+			want:   []result{{0, false}, {5, true}, {8, true}},
+		},
+		{
+			name:   "decreasing value without start time change",
+			points: []point{{1, 10, 100}, {1, 20, 50}, {1, 30, 70}},
+			want:   []result{{0, false}, {0, false}, {2, true}},
+		},
+		{
+			name:   "reset without start time",
+			points: []point{{0, 10, 100}, {0, 20, 50}},
+			want:   []result{{0, false}, {0, false}},
+		},
+		{
+			name:   "new start time before the previous point",
+			points: []point{{1, 10, 100}, {5, 20, 40}},
+			want:   []result{{0, false}, {0, false}},
+		},
+		{
+			name:   "duplicate and out-of-order points",
+			points: []point{{1, 10, 100}, {1, 10, 100}, {1, 5, 90}, {1, 20, 120}},
+			want:   []result{{0, false}, {0, false}, {0, false}, {2, true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newRateTracker(time.Minute)
+			for i, p := range tt.points {
+				dp := pmetric.NewNumberDataPoint()
+				dp.SetStartTimestamp(pcommon.Timestamp(p.start * int64(time.Second)))
+				dp.SetTimestamp(pcommon.Timestamp(p.time * int64(time.Second)))
+				dp.SetDoubleValue(p.value)
+
+				updates := make(rateUpdates)
+				rate, ok := tracker.rate("series", dp, updates)
+				tracker.commit(updates)
+
+				if ok != tt.want[i].ok || math.Abs(rate-tt.want[i].rate) > 1e-9 {
+					t.Errorf("point %d: got (%v, %v), want (%v, %v)", i, rate, ok, tt.want[i].rate, tt.want[i].ok)
+				}
+			}
+		})
+	}
+}
+
+func TestRateTrackerRetryUsesCommittedState(t *testing.T) {
+	tracker := newRateTracker(time.Minute)
+	newPoint := func(ts int64, value float64) pmetric.NumberDataPoint {
+		dp := pmetric.NewNumberDataPoint()
+		dp.SetStartTimestamp(pcommon.Timestamp(time.Second))
+		dp.SetTimestamp(pcommon.Timestamp(ts * int64(time.Second)))
+		dp.SetDoubleValue(value)
+		return dp
+	}
+
+	first := make(rateUpdates)
+	tracker.rate("series", newPoint(10, 100), first)
+	tracker.commit(first)
+
+	// A batch whose send failed is not committed, so its retry gets the same rate
+	for attempt := 0; attempt < 2; attempt++ {
+		rate, ok := tracker.rate("series", newPoint(20, 150), make(rateUpdates))
+		if !ok || rate != 5 {
+			t.Fatalf("attempt %d: got (%v, %v), want (5, true)", attempt, rate, ok)
+		}
+	}
+}
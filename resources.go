@@ -0,0 +1,129 @@
+package arcexporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// resourceIDColumn is the column referencing a normalized resource
+const resourceIDColumn = "resource_id"
+
+// resourceIDKeys marks the resource ID as a fixed column so it is never folded into the
+// JSON attributes column
+var resourceIDKeys = map[string]bool{resourceIDColumn: true}
+
+// sendFunc sends a payload to an Arc database
+type sendFunc func(ctx context.Context, database string, payload []byte) error
+
+// resourceID returns a stable hash identifying a resource by its attributes
+func resourceID(attrs map[string]interface{}) string {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	// Encoding a map of attribute values cannot fail
+	_ = enc.Encode(attrs)
+
+	h := fnv.New64a()
+	_, _ = h.Write(buf.Bytes())
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// resourceCache remembers which resources were recently written to the resources
+// measurement so unchanged resources are not re-sent on every push
+type resourceCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	lastSent map[string]time.Time
+}
+
+func newResourceCache(ttl time.Duration) *resourceCache {
+	return &resourceCache{
+		ttl:      ttl,
+		lastSent: make(map[string]time.Time),
+	}
+}
+
+// pending returns the distinct resources (keyed by resource ID) that have not been
+// sent within the refresh interval
+func (c *resourceCache) pending(resources []pcommon.Resource) map[string]map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	result := make(map[string]map[string]interface{})
+	for _, res := range resources {
+		attrs := attributesToMap(res.Attributes())
+		id := resourceID(attrs)
+		if sentAt, ok := c.lastSent[id]; ok && now.Sub(sentAt) < c.ttl {
+			continue
+		}
+		result[id] = attrs
+	}
+	return result
+}
+
+// markSent records the resources as written and drops expired entries
+func (c *resourceCache) markSent(resources map[string]map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for id, sentAt := range c.lastSent {
+		if now.Sub(sentAt) >= c.ttl {
+			delete(c.lastSent, id)
+		}
+	}
+	for id := range resources {
+		c.lastSent[id] = now
+	}
+}
+
+// resourcesToColumnar converts pending resources into one row per resource with the
+// resource ID and the resource attributes
+func resourcesToColumnar(config *Config, resources map[string]map[string]interface{}) ([]byte, error) {
+	now := toArcTime(pcommon.NewTimestampFromTime(time.Now()), config.TimestampPrecision)
+
+	times := make([]int64, 0, len(resources))
+	ids := make([]string, 0, len(resources))
+	allAttributes := make([]map[string]interface{}, 0, len(resources))
+	for id, attrs := range resources {
+		times = append(times, now)
+		ids = append(ids, id)
+		allAttributes = append(allAttributes, attrs)
+	}
+
+	columns := map[string]interface{}{
+		"time":           times,
+		resourceIDColumn: ids,
+	}
+	addAttributeColumns(config, columns, allAttributes, nil)
+
+	return encodeColumnar(config, config.ResourcesMeasurement, columns)
+}
+
+// sendResources writes the new or expired resources to the resources measurement of the
+// database. Resources are only marked as sent once Arc accepted them.
+func sendResources(ctx context.Context, config *Config, cache *resourceCache, database string, resources []pcommon.Resource, send sendFunc) error {
+	pending := cache.pending(resources)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	payload, err := resourcesToColumnar(config, pending)
+	if err != nil {
+		return fmt.Errorf("failed to convert resources: %w", err)
+	}
+	if err := send(ctx, database, payload); err != nil {
+		return fmt.Errorf("failed to send resources: %w", err)
+	}
+
+	cache.markSent(pending)
+	return nil
+}
@@ -0,0 +1,115 @@
+package arcexporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.uber.org/zap"
+)
+
+// maxErrorBodySize limits how much of an error response body is read
+const maxErrorBodySize = 64 * 1024
+
+// checkArcResponse converts a non-success Arc response into an error the exporterhelper
+// retry logic understands:
+//   - 4xx (except 408 and 429) are permanent and the data is dropped
+//   - 429 and 503 are throttled, honoring the server's Retry-After header
+//   - everything else (408, 5xx) is retried with the configured backoff
+func checkArcResponse(resp *http.Response, logger *zap.Logger) error {
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	err := fmt.Errorf("arc returned status %d: %s", resp.StatusCode, string(body))
+
+	fields := []zap.Field{zap.Int("status", resp.StatusCode)}
+	fields = append(fields, responseBodyFields(body)...)
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		logger.Warn("Arc is throttling requests", append(fields, zap.Duration("retry_after", retryAfter))...)
+		if retryAfter > 0 {
+			return exporterhelper.NewThrottleRetry(err, retryAfter)
+		}
+		return err
+	case resp.StatusCode == http.StatusRequestTimeout:
+		logger.Warn("Arc request timed out", fields...)
+		return err
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		logger.Error("Arc rejected the payload, dropping data", fields...)
+		return consumererror.NewPermanent(err)
+	default:
+		logger.Warn("Arc returned a retryable error", fields...)
+		return err
+	}
+}
+
+// joinSendErrors combines the errors of payloads sent independently of each other. The
+// result is permanent only if every error is; otherwise it is retryable (keeping any
+// throttle delay) and the permanent errors are only part of its message, since a wrapped
+// permanent error would make the exporterhelper drop the data instead of retrying it.
+func joinSendErrors(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	retryable := make([]error, 0, len(errs))
+	permanent := true
+	for _, err := range errs {
+		if consumererror.IsPermanent(err) {
+			err = errors.New(err.Error())
+		} else {
+			permanent = false
+		}
+		retryable = append(retryable, err)
+	}
+	if permanent {
+		return consumererror.NewPermanent(errors.Join(errs...))
+	}
+	return errors.Join(retryable...)
+}
+
+// responseBodyFields turns a JSON error body into log fields (one per top-level key),
+// falling back to the raw body when it is not a JSON object
+func responseBodyFields(body []byte) []zap.Field {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return []zap.Field{zap.ByteString("response_body", body)}
+	}
+
+	fields := make([]zap.Field, 0, len(parsed))
+	for k, v := range parsed {
+		fields = append(fields, zap.Any("arc_"+k, v))
+	}
+	return fields
+}
+
+// parseRetryAfter parses a Retry-After header given either as delay seconds or
+// as an HTTP date. It returns zero if the header is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
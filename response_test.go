@@ -0,0 +1,103 @@
+package arcexporter
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+func TestCheckArcResponse(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		retryAfter    string
+		wantErr       bool
+		wantPermanent bool
+		wantThrottle  bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "no content", status: http.StatusNoContent},
+		{name: "bad request", status: http.StatusBadRequest, wantErr: true, wantPermanent: true},
+		{name: "unauthorized", status: http.StatusUnauthorized, wantErr: true, wantPermanent: true},
+		{name: "too many requests", status: http.StatusTooManyRequests, retryAfter: "5", wantErr: true, wantThrottle: true},
+		{name: "too many requests without retry-after", status: http.StatusTooManyRequests, wantErr: true},
+		{name: "service unavailable", status: http.StatusServiceUnavailable, retryAfter: "2", wantErr: true, wantThrottle: true},
+		{name: "request timeout", status: http.StatusRequestTimeout, wantErr: true},
+		{name: "internal server error", status: http.StatusInternalServerError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.status,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader(`{"error":"rejected"}`)),
+			}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+
+			err := checkArcResponse(resp, zap.NewNop())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkArcResponse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			if got := consumererror.IsPermanent(err); got != tt.wantPermanent {
+				t.Errorf("permanent = %v, want %v (%v)", got, tt.wantPermanent, err)
+			}
+			if got := strings.HasPrefix(err.Error(), "Throttle"); got != tt.wantThrottle {
+				t.Errorf("throttled = %v, want %v (%v)", got, tt.wantThrottle, err)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		min   time.Duration
+		max   time.Duration
+	}{
+		{name: "missing", value: ""},
+		{name: "seconds", value: "30", min: 30 * time.Second, max: 30 * time.Second},
+		{name: "zero seconds", value: "0"},
+		{name: "negative seconds", value: "-5"},
+		{name: "invalid", value: "soon"},
+		{name: "future date", value: time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), min: 58 * time.Second, max: time.Minute},
+		{name: "past date", value: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got < tt.min || got > tt.max {
+				t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestJoinSendErrors(t *testing.T) {
+	permanent := consumererror.NewPermanent(errors.New("rejected"))
+	retryable := errors.New("unavailable")
+
+	if err := joinSendErrors(nil); err != nil {
+		t.Errorf("joinSendErrors(nil) = %v, want nil", err)
+	}
+	if err := joinSendErrors([]error{permanent, permanent}); !consumererror.IsPermanent(err) {
+		t.Errorf("only permanent errors: %v is not permanent", err)
+	}
+	err := joinSendErrors([]error{permanent, retryable})
+	if err == nil || consumererror.IsPermanent(err) {
+		t.Errorf("mixed errors: %v, want a retryable error", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "rejected") {
+		t.Errorf("mixed errors: %v does not report the permanent error", err)
+	}
+}
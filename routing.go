@@ -0,0 +1,129 @@
+package arcexporter
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// DatabaseRoutingConfig defines rules routing telemetry to Arc databases based on a
+// resource attribute, so one collector can split a batch into per-tenant payloads.
+type DatabaseRoutingConfig struct {
+	// ResourceAttribute is the resource attribute whose value selects the database
+	// (e.g. "tenant.id"). Routing is disabled when empty.
+	ResourceAttribute string `mapstructure:"resource_attribute"`
+
+	// Databases maps attribute values to database names. Mapped values are routed
+	// to their database; other values are used as the database name directly only if
+	// they are listed in Allowed or AllowUnlisted is set.
+	Databases map[string]string `mapstructure:"databases"`
+
+	// Allowed lists the database names rows may be routed to. When set, rows resolving
+	// to any other database go to Default, even if mapped in Databases.
+	Allowed []string `mapstructure:"allowed"`
+
+	// AllowUnlisted routes attribute values that are neither mapped nor allowed to a
+	// database of the same name. Anyone able to set the attribute can then write to
+	// (and create) arbitrary databases, so it is off by default and ignored when
+	// Allowed is set.
+	AllowUnlisted bool `mapstructure:"allow_unlisted"`
+
+	// Default is the database for rows without the attribute or with a database that
+	// is not mapped or allowed (defaults to the signal database)
+	Default string `mapstructure:"default"`
+}
+
+// databaseRouter resolves the Arc database for a resource
+type databaseRouter struct {
+	attribute       string
+	databases       map[string]string
+	allowed         map[string]bool
+	allowUnlisted   bool
+	defaultDatabase string
+}
+
+// newDatabaseRouter returns a router for the routing rules, or nil if routing is disabled.
+// signalDatabase is used when no default database is configured.
+func newDatabaseRouter(cfg DatabaseRoutingConfig, signalDatabase string) *databaseRouter {
+	if cfg.ResourceAttribute == "" {
+		return nil
+	}
+
+	router := &databaseRouter{
+		attribute:       cfg.ResourceAttribute,
+		databases:       cfg.Databases,
+		allowUnlisted:   cfg.AllowUnlisted,
+		defaultDatabase: cfg.Default,
+	}
+	if router.defaultDatabase == "" {
+		router.defaultDatabase = signalDatabase
+	}
+	if len(cfg.Allowed) > 0 {
+		router.allowed = make(map[string]bool, len(cfg.Allowed))
+		for _, database := range cfg.Allowed {
+			router.allowed[database] = true
+		}
+	}
+	return router
+}
+
+// route returns the database for the given resource
+func (r *databaseRouter) route(res pcommon.Resource) string {
+	value, ok := res.Attributes().Get(r.attribute)
+	if !ok || value.AsString() == "" {
+		return r.defaultDatabase
+	}
+
+	database, mapped := r.databases[value.AsString()]
+	if !mapped {
+		database = value.AsString()
+	}
+
+	switch {
+	case r.allowed != nil:
+		// An allow list restricts mapped and unmapped values alike
+		if !r.allowed[database] {
+			return r.defaultDatabase
+		}
+	case !mapped && !r.allowUnlisted:
+		return r.defaultDatabase
+	}
+	return database
+}
+
+// splitByDatabase groups the resources of a batch by the database they are routed to.
+// resources are the batch's resources in order; appendTo copies the i-th resource and
+// its data into the batch for its database, created with newBatch.
+func splitByDatabase[T any](r *databaseRouter, resources []pcommon.Resource, newBatch func() T, appendTo func(dest T, i int)) map[string]T {
+	result := make(map[string]T)
+	for i, res := range resources {
+		database := r.route(res)
+		routed, ok := result[database]
+		if !ok {
+			routed = newBatch()
+			result[database] = routed
+		}
+		appendTo(routed, i)
+	}
+	return result
+}
+
+// pushRouted sends the part of a batch routed to each database. Every database is sent
+// even if another one fails, so a tenant rejected by Arc never holds back the others.
+// It returns the databases whose part should be retried and the combined error, which
+// is permanent only if every failure is.
+func pushRouted[T any](ctx context.Context, routed map[string]T, push func(ctx context.Context, database string, batch T) error) ([]string, error) {
+	var retry []string
+	var errs []error
+	for database, batch := range routed {
+		if err := push(ctx, database, batch); err != nil {
+			errs = append(errs, fmt.Errorf("failed to push to database %s: %w", database, err))
+			if !consumererror.IsPermanent(err) {
+				retry = append(retry, database)
+			}
+		}
+	}
+	return retry, joinSendErrors(errs)
+}
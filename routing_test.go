@@ -0,0 +1,154 @@
+package arcexporter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestDatabaseRouterRoute(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    DatabaseRoutingConfig
+		tenant string
+		want   string
+	}{
+		{
+			name:   "missing attribute goes to default",
+			cfg:    DatabaseRoutingConfig{ResourceAttribute: "tenant.id", Default: "shared"},
+			tenant: "",
+			want:   "shared",
+		},
+		{
+			name:   "mapped value",
+			cfg:    DatabaseRoutingConfig{ResourceAttribute: "tenant.id", Databases: map[string]string{"acme": "tenant_acme"}},
+			tenant: "acme",
+			want:   "tenant_acme",
+		},
+		{
+			name:   "unlisted value goes to default",
+			cfg:    DatabaseRoutingConfig{ResourceAttribute: "tenant.id", Databases: map[string]string{"acme": "tenant_acme"}},
+			tenant: "evil",
+			want:   "metrics",
+		},
+		{
+			name:   "allowed value",
+			cfg:    DatabaseRoutingConfig{ResourceAttribute: "tenant.id", Allowed: []string{"tenant_globex"}},
+			tenant: "tenant_globex",
+			want:   "tenant_globex",
+		},
+		{
+			name: "mapped value outside allow list goes to default",
+			cfg: DatabaseRoutingConfig{
+				ResourceAttribute: "tenant.id",
+				Databases:         map[string]string{"acme": "tenant_acme"},
+				Allowed:           []string{"tenant_globex"},
+			},
+			tenant: "acme",
+			want:   "metrics",
+		},
+		{
+			name:   "allow unlisted",
+			cfg:    DatabaseRoutingConfig{ResourceAttribute: "tenant.id", AllowUnlisted: true},
+			tenant: "anything",
+			want:   "anything",
+		},
+		{
+			name:   "allow list wins over allow unlisted",
+			cfg:    DatabaseRoutingConfig{ResourceAttribute: "tenant.id", Allowed: []string{"tenant_acme"}, AllowUnlisted: true},
+			tenant: "anything",
+			want:   "metrics",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newDatabaseRouter(tt.cfg, "metrics")
+			res := pcommon.NewResource()
+			if tt.tenant != "" {
+				res.Attributes().PutStr("tenant.id", tt.tenant)
+			}
+			if got := router.route(res); got != tt.want {
+				t.Errorf("route() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPushTracesRoutesEveryDatabase(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		database := r.Header.Get("X-Arc-Database")
+		switch database {
+		case "bad":
+			w.WriteHeader(http.StatusBadRequest)
+		case "flaky":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			mu.Lock()
+			received[database]++
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	cfg := newTestConfig(t, func(cfg *Config) {
+		cfg.Endpoint = server.URL
+		cfg.DatabaseRouting = DatabaseRoutingConfig{ResourceAttribute: "tenant.id", AllowUnlisted: true}
+	})
+	exp, err := newTracesExporter(cfg, exportertest.NewNopCreateSettings())
+	if err != nil {
+		t.Fatalf("newTracesExporter: %v", err)
+	}
+	exp.client = server.Client()
+
+	newTraces := func(tenants ...string) ptrace.Traces {
+		td := ptrace.NewTraces()
+		for _, tenant := range tenants {
+			rs := td.ResourceSpans().AppendEmpty()
+			rs.Resource().Attributes().PutStr("tenant.id", tenant)
+			rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("op")
+		}
+		return td
+	}
+
+	t.Run("permanent failures only", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			if err := exp.pushTraces(context.Background(), newTraces("bad", "a", "b", "c")); !consumererror.IsPermanent(err) {
+				t.Fatalf("pushTraces() = %v, want a permanent error", err)
+			}
+		}
+		for _, tenant := range []string{"a", "b", "c"} {
+			if received[tenant] != 20 {
+				t.Errorf("tenant %s received %d payloads, want 20", tenant, received[tenant])
+			}
+		}
+	})
+
+	t.Run("retryable failure keeps only its resources", func(t *testing.T) {
+		err := exp.pushTraces(context.Background(), newTraces("bad", "flaky", "a"))
+		if err == nil || consumererror.IsPermanent(err) {
+			t.Fatalf("pushTraces() = %v, want a retryable error", err)
+		}
+		var tracesErr consumererror.Traces
+		if !errors.As(err, &tracesErr) {
+			t.Fatalf("pushTraces() = %v, want an error carrying the failed traces", err)
+		}
+		failed := tracesErr.Data()
+		if failed.ResourceSpans().Len() != 1 {
+			t.Fatalf("retrying %d resources, want 1", failed.ResourceSpans().Len())
+		}
+		if tenant, _ := failed.ResourceSpans().At(0).Resource().Attributes().Get("tenant.id"); tenant.AsString() != "flaky" {
+			t.Errorf("retrying tenant %q, want flaky", tenant.AsString())
+		}
+	})
+}
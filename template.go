@@ -0,0 +1,98 @@
+package arcexporter
+
+import (
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// measurementTemplate resolves measurement names containing ${resource.<key>} and
+// ${attr.<key>} placeholders, e.g. "logs_${resource.service.name}"
+type measurementTemplate struct {
+	parts    []templatePart
+	static   bool
+	fallback string
+}
+
+// templatePart is either a literal string or a placeholder reading an attribute
+type templatePart struct {
+	literal  string
+	resource bool
+	key      string
+}
+
+// parseMeasurementTemplate parses a measurement name template. fallback is used when a
+// placeholder cannot be resolved.
+func parseMeasurementTemplate(template, fallback string) (*measurementTemplate, error) {
+	t := &measurementTemplate{fallback: fallback}
+
+	rest := template
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			if rest != "" {
+				t.parts = append(t.parts, templatePart{literal: rest})
+			}
+			break
+		}
+		if start > 0 {
+			t.parts = append(t.parts, templatePart{literal: rest[:start]})
+		}
+
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in measurement template %q", template)
+		}
+		placeholder := rest[start+2 : start+end]
+		rest = rest[start+end+1:]
+
+		switch {
+		case strings.HasPrefix(placeholder, "resource.") && len(placeholder) > len("resource."):
+			t.parts = append(t.parts, templatePart{resource: true, key: strings.TrimPrefix(placeholder, "resource.")})
+		case strings.HasPrefix(placeholder, "attr.") && len(placeholder) > len("attr."):
+			t.parts = append(t.parts, templatePart{key: strings.TrimPrefix(placeholder, "attr.")})
+		default:
+			return nil, fmt.Errorf("invalid placeholder ${%s} in measurement template %q (expected ${resource.<key>} or ${attr.<key>})", placeholder, template)
+		}
+	}
+
+	t.static = true
+	for _, part := range t.parts {
+		if part.key != "" {
+			t.static = false
+		}
+	}
+	if t.static {
+		t.fallback = template
+	}
+	return t, nil
+}
+
+// resolve returns the measurement name for a record with the given resource and record
+// attributes. Templated names are sanitized like metric names; the fallback is returned if
+// any placeholder is missing or empty.
+func (t *measurementTemplate) resolve(resourceAttrs, attrs pcommon.Map) string {
+	if t.static {
+		return t.fallback
+	}
+
+	var sb strings.Builder
+	for _, part := range t.parts {
+		if part.key == "" {
+			sb.WriteString(part.literal)
+			continue
+		}
+
+		source := attrs
+		if part.resource {
+			source = resourceAttrs
+		}
+		value, ok := source.Get(part.key)
+		if !ok || value.AsString() == "" {
+			return t.fallback
+		}
+		sb.WriteString(value.AsString())
+	}
+	return sanitizeMetricName(sb.String())
+}
@@ -0,0 +1,79 @@
+package arcexporter
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestParseMeasurementTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{name: "unclosed placeholder", template: "logs_${resource.service.name"},
+		{name: "unknown prefix", template: "logs_${span.name}"},
+		{name: "empty resource key", template: "logs_${resource.}"},
+		{name: "empty attr key", template: "logs_${attr.}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseMeasurementTemplate(tt.template, "logs"); err == nil {
+				t.Errorf("parseMeasurementTemplate(%q) succeeded, want error", tt.template)
+			}
+		})
+	}
+}
+
+func TestMeasurementTemplateResolve(t *testing.T) {
+	resourceAttrs := pcommon.NewMap()
+	resourceAttrs.PutStr("service.name", "api-gateway")
+	resourceAttrs.PutStr("empty", "")
+	attrs := pcommon.NewMap()
+	attrs.PutStr("event.domain", "auth")
+	attrs.PutInt("shard", 3)
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "static name", template: "logs", want: "logs"},
+		{name: "static name is not sanitized", template: "my.logs", want: "my.logs"},
+		{name: "resource placeholder", template: "logs_${resource.service.name}", want: "logs_api_gateway"},
+		{name: "attr placeholder", template: "logs_${attr.event.domain}", want: "logs_auth"},
+		{name: "non-string attr", template: "logs_${attr.shard}", want: "logs_3"},
+		{name: "several placeholders", template: "${resource.service.name}.${attr.event.domain}", want: "api_gateway_auth"},
+		{name: "missing value uses fallback", template: "logs_${attr.missing}", want: "fallback_logs"},
+		{name: "empty value uses fallback", template: "logs_${resource.empty}", want: "fallback_logs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := parseMeasurementTemplate(tt.template, "fallback_logs")
+			if err != nil {
+				t.Fatalf("parseMeasurementTemplate(%q): %v", tt.template, err)
+			}
+			if got := template.resolve(resourceAttrs, attrs); got != tt.want {
+				t.Errorf("resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewExportersParseMeasurementTemplates(t *testing.T) {
+	// Exporters must not depend on Validate having parsed the templates
+	cfg := createDefaultConfig().(*Config)
+	cfg.TracesMeasurement = "traces_${resource.service.name}"
+	if exp, err := newTracesExporter(cfg, exportertest.NewNopCreateSettings()); err != nil || exp.measurement.static {
+		t.Errorf("newTracesExporter() = %v, want a templated measurement", err)
+	}
+	if exp, err := newLogsExporter(cfg, exportertest.NewNopCreateSettings()); err != nil || !exp.measurement.static {
+		t.Errorf("newLogsExporter() = %v, want a static measurement", err)
+	}
+
+	cfg.LogsMeasurement = "logs_${span.name}"
+	if _, err := newLogsExporter(cfg, exportertest.NewNopCreateSettings()); err == nil {
+		t.Error("newLogsExporter() succeeded with an invalid template, want error")
+	}
+}
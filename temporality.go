@@ -0,0 +1,331 @@
+package arcexporter
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// seriesKey identifies a metric series by metric name and its full label set
+// (resource, scope and data point attributes)
+func seriesKey(metricName string, labels map[string]interface{}) string {
+	return metricName + "/" + resourceID(labels)
+}
+
+// temporalityState is the last point (cumulative to delta) or the running total
+// (delta to cumulative) of a sum or histogram series
+type temporalityState struct {
+	start pcommon.Timestamp
+	time  pcommon.Timestamp
+
+	isInt    bool
+	value    float64
+	intValue int64
+
+	count   uint64
+	sum     float64
+	min     float64
+	max     float64
+	hasMin  bool
+	hasMax  bool
+	bounds  []float64
+	buckets []uint64
+
+	lastSeen time.Time
+}
+
+// temporalityConverter converts sums and explicit-bucket histograms to a single
+// aggregation temporality. It keeps one state per series; series not seen within
+// the TTL are forgotten.
+type temporalityConverter struct {
+	mu     sync.Mutex
+	target pmetric.AggregationTemporality
+	ttl    time.Duration
+	series map[string]*temporalityState
+}
+
+// newTemporalityConverter returns nil if data should keep its original temporality
+func newTemporalityConverter(target string, ttl time.Duration) *temporalityConverter {
+	c := &temporalityConverter{
+		ttl:    ttl,
+		series: make(map[string]*temporalityState),
+	}
+	switch target {
+	case aggregationTemporalityCumulative:
+		c.target = pmetric.AggregationTemporalityCumulative
+	case aggregationTemporalityDelta:
+		c.target = pmetric.AggregationTemporalityDelta
+	default:
+		return nil
+	}
+	return c
+}
+
+// temporality returns the temporality of converted points of the given source temporality
+func (c *temporalityConverter) temporality(from pmetric.AggregationTemporality) pmetric.AggregationTemporality {
+	if c == nil || from == pmetric.AggregationTemporalityUnspecified {
+		return from
+	}
+	return c.target
+}
+
+// temporalityUpdates holds the series states changed while converting one batch. They
+// are committed once the batch was sent, so a retried batch is converted the same way.
+type temporalityUpdates map[string]*temporalityState
+
+// lookup returns the state of a series as seen by the current batch: its pending update
+// or a copy of the committed state that can be modified and recorded in updates
+func (c *temporalityConverter) lookup(key string, updates temporalityUpdates) (*temporalityState, bool) {
+	if state, ok := updates[key]; ok {
+		return state, true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	committed, ok := c.series[key]
+	if !ok {
+		return nil, false
+	}
+	state := *committed
+	state.bounds = append([]float64(nil), committed.bounds...)
+	state.buckets = append([]uint64(nil), committed.buckets...)
+	return &state, true
+}
+
+// commit applies the series states of a batch that was sent successfully
+func (c *temporalityConverter) commit(updates temporalityUpdates) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, state := range updates {
+		c.series[key] = state
+	}
+}
+
+// prune drops series not updated within the TTL
+func (c *temporalityConverter) prune() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, state := range c.series {
+		if now.Sub(state.lastSeen) >= c.ttl {
+			delete(c.series, key)
+		}
+	}
+}
+
+// convertNumber converts a sum data point to the target temporality. It returns false
+// if no point should be written: the first cumulative point of a series only sets the
+// baseline, and duplicate or out-of-order points are dropped. The new series state is
+// recorded in updates and only takes effect once committed.
+func (c *temporalityConverter) convertNumber(key string, dp pmetric.NumberDataPoint, from pmetric.AggregationTemporality, monotonic bool, updates temporalityUpdates) (pmetric.NumberDataPoint, bool) {
+	if from == c.target || from == pmetric.AggregationTemporalityUnspecified {
+		return dp, true
+	}
+
+	isInt := dp.ValueType() == pmetric.NumberDataPointValueTypeInt
+	state, ok := c.lookup(key, updates)
+	if ok && dp.Timestamp() <= state.time {
+		return dp, false
+	}
+	if ok {
+		state.lastSeen = time.Now()
+		updates[key] = state
+	}
+
+	// Restart the series if the value type changed
+	if !ok || state.isInt != isInt {
+		state = &temporalityState{isInt: isInt, start: dp.StartTimestamp(), lastSeen: time.Now()}
+		updates[key] = state
+		if from == pmetric.AggregationTemporalityCumulative {
+			state.time = dp.Timestamp()
+			state.value = dp.DoubleValue()
+			state.intValue = dp.IntValue()
+			return dp, false
+		}
+	}
+
+	out := pmetric.NewNumberDataPoint()
+	dp.CopyTo(out)
+
+	if from == pmetric.AggregationTemporalityCumulative {
+		// A new start time or a decreasing counter means the source restarted from zero
+		reset := dp.StartTimestamp() != state.start ||
+			(monotonic && (isInt && dp.IntValue() < state.intValue || !isInt && dp.DoubleValue() < state.value))
+
+		out.SetStartTimestamp(state.deltaStart(dp.StartTimestamp(), reset))
+		switch {
+		case reset && isInt:
+			out.SetIntValue(dp.IntValue())
+		case reset:
+			out.SetDoubleValue(dp.DoubleValue())
+		case isInt:
+			out.SetIntValue(dp.IntValue() - state.intValue)
+		default:
+			out.SetDoubleValue(dp.DoubleValue() - state.value)
+		}
+
+		state.start = dp.StartTimestamp()
+		state.time = dp.Timestamp()
+		state.value = dp.DoubleValue()
+		state.intValue = dp.IntValue()
+		return out, true
+	}
+
+	// Delta to cumulative: accumulate since the first point of the series
+	if state.time != 0 {
+		state.value += dp.DoubleValue()
+		state.intValue += dp.IntValue()
+	} else {
+		state.value = dp.DoubleValue()
+		state.intValue = dp.IntValue()
+	}
+	state.time = dp.Timestamp()
+
+	out.SetStartTimestamp(state.start)
+	if isInt {
+		out.SetIntValue(state.intValue)
+	} else {
+		out.SetDoubleValue(state.value)
+	}
+	return out, true
+}
+
+// convertHistogram converts a histogram data point to the target temporality, following
+// the same rules as convertNumber. Min and max cannot be derived for delta points from
+// cumulative data and are removed; cumulative points keep the running min and max.
+// Count and buckets are always converted; a non-finite sum only makes the converted sum
+// non-finite, so the non-finite policy can drop or null it.
+func (c *temporalityConverter) convertHistogram(key string, dp pmetric.HistogramDataPoint, from pmetric.AggregationTemporality, updates temporalityUpdates) (pmetric.HistogramDataPoint, bool) {
+	if from == c.target || from == pmetric.AggregationTemporalityUnspecified {
+		return dp, true
+	}
+
+	state, ok := c.lookup(key, updates)
+	if ok && dp.Timestamp() <= state.time {
+		return dp, false
+	}
+	if ok {
+		state.lastSeen = time.Now()
+		updates[key] = state
+	}
+
+	// Restart the series if the bucket layout changed
+	if !ok || !equalBounds(state.bounds, dp.ExplicitBounds()) {
+		state = &temporalityState{
+			start:    dp.StartTimestamp(),
+			bounds:   dp.ExplicitBounds().AsRaw(),
+			lastSeen: time.Now(),
+		}
+		updates[key] = state
+		if from == pmetric.AggregationTemporalityCumulative {
+			state.setHistogram(dp)
+			return dp, false
+		}
+	}
+
+	out := pmetric.NewHistogramDataPoint()
+	dp.CopyTo(out)
+
+	if from == pmetric.AggregationTemporalityCumulative {
+		// A new start time or a decreasing count or bucket means the source restarted from zero
+		reset := dp.StartTimestamp() != state.start || dp.Count() < state.count
+		for i := 0; i < dp.BucketCounts().Len() && i < len(state.buckets); i++ {
+			if dp.BucketCounts().At(i) < state.buckets[i] {
+				reset = true
+			}
+		}
+
+		out.SetStartTimestamp(state.deltaStart(dp.StartTimestamp(), reset))
+		out.RemoveMin()
+		out.RemoveMax()
+		if !reset {
+			out.SetCount(dp.Count() - state.count)
+			out.SetSum(dp.Sum() - state.sum)
+			for i := 0; i < out.BucketCounts().Len() && i < len(state.buckets); i++ {
+				out.BucketCounts().SetAt(i, dp.BucketCounts().At(i)-state.buckets[i])
+			}
+		}
+
+		state.start = dp.StartTimestamp()
+		state.setHistogram(dp)
+		return out, true
+	}
+
+	// Delta to cumulative: accumulate since the first point of the series. A non-finite
+	// sum is passed on for this point only and left out of the running sum.
+	if state.time != 0 {
+		state.count += dp.Count()
+		if isFinite(dp.Sum()) {
+			state.sum += dp.Sum()
+		}
+		for i := 0; i < dp.BucketCounts().Len() && i < len(state.buckets); i++ {
+			state.buckets[i] += dp.BucketCounts().At(i)
+		}
+		if dp.HasMin() && (!state.hasMin || dp.Min() < state.min) {
+			state.min, state.hasMin = dp.Min(), true
+		}
+		if dp.HasMax() && (!state.hasMax || dp.Max() > state.max) {
+			state.max, state.hasMax = dp.Max(), true
+		}
+		state.time = dp.Timestamp()
+	} else {
+		state.setHistogram(dp)
+		if !isFinite(state.sum) {
+			state.sum = 0
+		}
+	}
+
+	out.SetStartTimestamp(state.start)
+	out.SetCount(state.count)
+	if isFinite(dp.Sum()) {
+		out.SetSum(state.sum)
+	}
+	out.BucketCounts().FromRaw(state.buckets)
+	out.RemoveMin()
+	out.RemoveMax()
+	if state.hasMin {
+		out.SetMin(state.min)
+	}
+	if state.hasMax {
+		out.SetMax(state.max)
+	}
+	return out, true
+}
+
+// deltaStart returns the start time of a delta point converted from a cumulative point.
+// After a reset the value is counted from the source's new start time; if the start time
+// did not change (a decreasing counter), the restart happened after the previous point.
+func (s *temporalityState) deltaStart(start pcommon.Timestamp, reset bool) pcommon.Timestamp {
+	if reset && start != s.start {
+		return start
+	}
+	return s.time
+}
+
+// setHistogram stores the values of a histogram data point
+func (s *temporalityState) setHistogram(dp pmetric.HistogramDataPoint) {
+	s.time = dp.Timestamp()
+	s.count = dp.Count()
+	s.sum = dp.Sum()
+	s.buckets = dp.BucketCounts().AsRaw()
+	s.min, s.hasMin = dp.Min(), dp.HasMin()
+	s.max, s.hasMax = dp.Max(), dp.HasMax()
+}
+
+// equalBounds reports whether the stored bucket bounds match the data point's bounds
+func equalBounds(stored []float64, bounds pcommon.Float64Slice) bool {
+	if len(stored) != bounds.Len() {
+		return false
+	}
+	for i, bound := range stored {
+		if bound != bounds.At(i) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,234 @@
+package arcexporter
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// numberPoint is a sum data point in tests; times are in seconds
+type numberPoint struct {
+	start int64
+	time  int64
+	value int64
+}
+
+func (p numberPoint) dataPoint() pmetric.NumberDataPoint {
+	dp := pmetric.NewNumberDataPoint()
+	dp.SetStartTimestamp(pcommon.Timestamp(p.start * int64(time.Second)))
+	dp.SetTimestamp(pcommon.Timestamp(p.time * int64(time.Second)))
+	dp.SetIntValue(p.value)
+	return dp
+}
+
+// convertedPoint is the expected result of a conversion; ok is false if nothing is written
+type convertedPoint struct {
+	ok    bool
+	start int64
+	value int64
+}
+
+func TestTemporalityConverterConvertNumber(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		from   pmetric.AggregationTemporality
+		points []numberPoint
+		want   []convertedPoint
+	}{
+		{
+			name:   "cumulative to delta",
+			target: aggregationTemporalityDelta,
+			from:   pmetric.AggregationTemporalityCumulative,
+			points: []numberPoint{{1, 10, 5}, {1, 20, 8}, {1, 30, 15}},
+			want:   []convertedPoint{{ok: false}, {ok: true, start: 10, value: 3}, {ok: true, start: 20, value: 7}},
+		},
+		{
+			name:   "reset by decreasing value",
+			target: aggregationTemporalityDelta,
+			from:   pmetric.AggregationTemporalityCumulative,
+			points: []numberPoint{{1, 10, 5}, {1, 20, 8}, {1, 30, 2}},
+			want:   []convertedPoint{{ok: false}, {ok: true, start: 10, value: 3}, {ok: true, start: 20, value: 2}},
+		},
+		{
+			name:   "reset by new start time",
+			target: aggregationTemporalityDelta,
+			from:   pmetric.AggregationTemporalityCumulative,
+			points: []numberPoint{{1, 10, 5}, {1, 20, 8}, {25, 30, 9}},
+			want:   []convertedPoint{{ok: false}, {ok: true, start: 10, value: 3}, {ok: true, start: 25, value: 9}},
+		},
+		{
+			name:   "duplicate and out-of-order points are dropped",
+			target: aggregationTemporalityDelta,
+			from:   pmetric.AggregationTemporalityCumulative,
+			points: []numberPoint{{1, 10, 5}, {1, 20, 8}, {1, 20, 8}, {1, 15, 6}, {1, 30, 10}},
+			want:   []convertedPoint{{ok: false}, {ok: true, start: 10, value: 3}, {ok: false}, {ok: false}, {ok: true, start: 20, value: 2}},
+		},
+		{
+			name:   "delta to cumulative",
+			target: aggregationTemporalityCumulative,
+			from:   pmetric.AggregationTemporalityDelta,
+			points: []numberPoint{{0, 10, 5}, {10, 20, 3}, {20, 30, 4}},
+			want:   []convertedPoint{{ok: true, start: 0, value: 5}, {ok: true, start: 0, value: 8}, {ok: true, start: 0, value: 12}},
+		},
+		{
+			name:   "matching temporality is unchanged",
+			target: aggregationTemporalityDelta,
+			from:   pmetric.AggregationTemporalityDelta,
+			points: []numberPoint{{0, 10, 5}, {10, 20, 3}},
+			want:   []convertedPoint{{ok: true, start: 0, value: 5}, {ok: true, start: 10, value: 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTemporalityConverter(tt.target, time.Minute)
+			for i, p := range tt.points {
+				updates := make(temporalityUpdates)
+				out, ok := c.convertNumber("series", p.dataPoint(), tt.from, true, updates)
+				c.commit(updates)
+
+				want := tt.want[i]
+				if ok != want.ok {
+					t.Fatalf("point %d: ok = %v, want %v", i, ok, want.ok)
+				}
+				if !ok {
+					continue
+				}
+				if got := int64(out.StartTimestamp()) / int64(time.Second); got != want.start {
+					t.Errorf("point %d: start = %d, want %d", i, got, want.start)
+				}
+				if out.IntValue() != want.value {
+					t.Errorf("point %d: value = %d, want %d", i, out.IntValue(), want.value)
+				}
+			}
+		})
+	}
+}
+
+func TestTemporalityConverterRetryUsesCommittedState(t *testing.T) {
+	c := newTemporalityConverter(aggregationTemporalityDelta, time.Minute)
+	first := make(temporalityUpdates)
+	c.convertNumber("series", numberPoint{1, 10, 5}.dataPoint(), pmetric.AggregationTemporalityCumulative, true, first)
+	c.commit(first)
+
+	// A batch whose send failed is not committed, so its retry converts the same way
+	for attempt := 0; attempt < 2; attempt++ {
+		updates := make(temporalityUpdates)
+		out, ok := c.convertNumber("series", numberPoint{1, 20, 8}.dataPoint(), pmetric.AggregationTemporalityCumulative, true, updates)
+		if !ok || out.IntValue() != 3 {
+			t.Fatalf("attempt %d: got (%d, %v), want (3, true)", attempt, out.IntValue(), ok)
+		}
+	}
+}
+
+func TestTemporalityConverterConvertHistogram(t *testing.T) {
+	newDataPoint := func(start, ts int64, counts []uint64, sum float64) pmetric.HistogramDataPoint {
+		dp := pmetric.NewHistogramDataPoint()
+		dp.SetStartTimestamp(pcommon.Timestamp(start * int64(time.Second)))
+		dp.SetTimestamp(pcommon.Timestamp(ts * int64(time.Second)))
+		dp.ExplicitBounds().FromRaw([]float64{10})
+		dp.BucketCounts().FromRaw(counts)
+		var count uint64
+		for _, c := range counts {
+			count += c
+		}
+		dp.SetCount(count)
+		dp.SetSum(sum)
+		dp.SetMin(1)
+		dp.SetMax(20)
+		return dp
+	}
+
+	t.Run("cumulative to delta", func(t *testing.T) {
+		c := newTemporalityConverter(aggregationTemporalityDelta, time.Minute)
+		points := []pmetric.HistogramDataPoint{
+			newDataPoint(1, 10, []uint64{2, 1}, 30),
+			newDataPoint(1, 20, []uint64{5, 3}, 90),
+			newDataPoint(25, 30, []uint64{1, 0}, 4),
+		}
+		want := []struct {
+			ok      bool
+			count   uint64
+			sum     float64
+			buckets []uint64
+		}{
+			{ok: false},
+			{ok: true, count: 5, sum: 60, buckets: []uint64{3, 2}},
+			{ok: true, count: 1, sum: 4, buckets: []uint64{1, 0}},
+		}
+		for i, dp := range points {
+			updates := make(temporalityUpdates)
+			out, ok := c.convertHistogram("series", dp, pmetric.AggregationTemporalityCumulative, updates)
+			c.commit(updates)
+			if ok != want[i].ok {
+				t.Fatalf("point %d: ok = %v, want %v", i, ok, want[i].ok)
+			}
+			if !ok {
+				continue
+			}
+			if out.Count() != want[i].count || out.Sum() != want[i].sum {
+				t.Errorf("point %d: count, sum = %d, %v, want %d, %v", i, out.Count(), out.Sum(), want[i].count, want[i].sum)
+			}
+			if got := out.BucketCounts().AsRaw(); got[0] != want[i].buckets[0] || got[1] != want[i].buckets[1] {
+				t.Errorf("point %d: buckets = %v, want %v", i, got, want[i].buckets)
+			}
+			if out.HasMin() || out.HasMax() {
+				t.Errorf("point %d: delta point keeps min/max", i)
+			}
+		}
+	})
+
+	t.Run("decreasing bucket is a reset", func(t *testing.T) {
+		c := newTemporalityConverter(aggregationTemporalityDelta, time.Minute)
+		updates := make(temporalityUpdates)
+		c.convertHistogram("series", newDataPoint(0, 10, []uint64{5, 0}, 30), pmetric.AggregationTemporalityCumulative, updates)
+
+		out, ok := c.convertHistogram("series", newDataPoint(0, 20, []uint64{0, 9}, 90), pmetric.AggregationTemporalityCumulative, updates)
+		if !ok {
+			t.Fatal("point dropped")
+		}
+		if got := out.BucketCounts().AsRaw(); got[0] != 0 || got[1] != 9 {
+			t.Errorf("buckets = %v, want [0 9]", got)
+		}
+		if out.Count() != 9 || out.Sum() != 90 {
+			t.Errorf("count, sum = %d, %v, want 9, 90", out.Count(), out.Sum())
+		}
+	})
+
+	t.Run("delta to cumulative", func(t *testing.T) {
+		c := newTemporalityConverter(aggregationTemporalityCumulative, time.Minute)
+		var out pmetric.HistogramDataPoint
+		for i, dp := range []pmetric.HistogramDataPoint{
+			newDataPoint(0, 10, []uint64{2, 1}, 30),
+			newDataPoint(10, 20, []uint64{1, 1}, 25),
+		} {
+			updates := make(temporalityUpdates)
+			var ok bool
+			out, ok = c.convertHistogram("series", dp, pmetric.AggregationTemporalityDelta, updates)
+			c.commit(updates)
+			if !ok {
+				t.Fatalf("point %d dropped", i)
+			}
+		}
+		if out.Count() != 5 || out.Sum() != 55 || out.StartTimestamp() != 0 {
+			t.Errorf("count, sum, start = %d, %v, %d, want 5, 55, 0", out.Count(), out.Sum(), out.StartTimestamp())
+		}
+		if got := out.BucketCounts().AsRaw(); got[0] != 3 || got[1] != 2 {
+			t.Errorf("buckets = %v, want [3 2]", got)
+		}
+	})
+
+	t.Run("changed bounds restart the series", func(t *testing.T) {
+		c := newTemporalityConverter(aggregationTemporalityDelta, time.Minute)
+		updates := make(temporalityUpdates)
+		c.convertHistogram("series", newDataPoint(1, 10, []uint64{2, 1}, 30), pmetric.AggregationTemporalityCumulative, updates)
+
+		dp := newDataPoint(1, 20, []uint64{2, 1, 1}, 40)
+		dp.ExplicitBounds().FromRaw([]float64{10, 20})
+		if _, ok := c.convertHistogram("series", dp, pmetric.AggregationTemporalityCumulative, updates); ok {
+			t.Error("point with new bounds was converted, want a new baseline")
+		}
+	})
+}
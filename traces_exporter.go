@@ -2,13 +2,12 @@ package arcexporter
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
 	"fmt"
-	"io"
 	"net/http"
 
-	"github.com/vmihailenco/msgpack/v5"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
@@ -16,44 +15,199 @@ import (
 )
 
 type tracesExporter struct {
-	config *Config
-	client *http.Client
-	logger *zap.Logger
+	config   *Config
+	client   *http.Client
+	logger   *zap.Logger
+	settings component.TelemetrySettings
+
+	// resources caches normalized resources (nil unless resources_measurement is set)
+	resources *resourceCache
+
+	// router resolves per-resource databases (nil unless database_routing is set)
+	router *databaseRouter
+
+	// measurement resolves the measurement name of each span
+	measurement *measurementTemplate
 }
 
-func newTracesExporter(config *Config, set exporter.CreateSettings) *tracesExporter {
-	return &tracesExporter{
-		config: config,
-		client: &http.Client{
-			Timeout: config.Timeout,
-		},
-		logger: set.Logger,
+func newTracesExporter(config *Config, set exporter.CreateSettings) (*tracesExporter, error) {
+	measurement, err := config.tracesMeasurementTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &tracesExporter{
+		config:      config,
+		logger:      set.Logger,
+		settings:    set.TelemetrySettings,
+		router:      newDatabaseRouter(config.DatabaseRouting, config.TracesDatabase),
+		measurement: measurement,
 	}
+	if config.ResourcesMeasurement != "" {
+		exp.resources = newResourceCache(config.ResourcesRefreshInterval)
+	}
+	return exp, nil
 }
 
-func (e *tracesExporter) pushTraces(ctx context.Context, td ptrace.Traces) error {
-	// Convert OTel traces to Arc columnar format
-	payload, err := e.tracesToColumnar(td)
+// start prepares compression and creates the HTTP client
+func (e *tracesExporter) start(_ context.Context, host component.Host) error {
+	client, err := startClient(e.config, host, e.settings)
 	if err != nil {
-		return fmt.Errorf("failed to convert traces: %w", err)
+		return err
+	}
+	e.client = client
+	return nil
+}
+
+func (e *tracesExporter) pushTraces(ctx context.Context, td ptrace.Traces) error {
+	// Without routing rules everything goes to the traces database
+	if e.router == nil {
+		return e.pushTracesToDatabase(ctx, e.config.TracesDatabase, td)
+	}
+
+	routed := splitByDatabase(e.router, e.resourcesOf(td), ptrace.NewTraces, func(dest ptrace.Traces, i int) {
+		td.ResourceSpans().At(i).CopyTo(dest.ResourceSpans().AppendEmpty())
+	})
+	retry, err := pushRouted(ctx, routed, e.pushTracesToDatabase)
+	if len(retry) == 0 {
+		return err
 	}
 
-	// Send to Arc
-	return e.sendToArc(ctx, payload)
+	// Only the resources of databases that failed with a retryable error are retried
+	failed := ptrace.NewTraces()
+	for _, database := range retry {
+		routed[database].ResourceSpans().MoveAndAppendTo(failed.ResourceSpans())
+	}
+	return consumererror.NewTraces(err, failed)
+}
+
+// resourcesOf returns the resource of each resource spans in the batch
+func (e *tracesExporter) resourcesOf(td ptrace.Traces) []pcommon.Resource {
+	resources := make([]pcommon.Resource, 0, td.ResourceSpans().Len())
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		resources = append(resources, td.ResourceSpans().At(i).Resource())
+	}
+	return resources
 }
 
-func (e *tracesExporter) tracesToColumnar(td ptrace.Traces) ([]byte, error) {
+func (e *tracesExporter) pushTracesToDatabase(ctx context.Context, database string, td ptrace.Traces) error {
+	// Send new or expired resources first so rows never reference an unknown resource
+	if e.resources != nil {
+		if err := sendResources(ctx, e.config, e.resources, database, e.resourcesOf(td), e.sendToArc); err != nil {
+			return err
+		}
+	}
+
+	// Convert OTel traces to Arc columnar format, one payload per measurement
+	for measurement, traces := range e.splitByMeasurement(td) {
+		payload, err := e.tracesToColumnar(traces, measurement)
+		if err != nil {
+			return fmt.Errorf("failed to convert traces: %w", err)
+		}
+
+		// Send to Arc
+		if err := e.sendToArc(ctx, database, payload); err != nil {
+			return err
+		}
+	}
+
+	// Span events go to their own measurement when configured
+	if e.config.SpanEventsMeasurement != "" {
+		eventsPayload, err := e.spanEventsToColumnar(td)
+		if err != nil {
+			return fmt.Errorf("failed to convert span events: %w", err)
+		}
+		if eventsPayload != nil {
+			if err := e.sendToArc(ctx, database, eventsPayload); err != nil {
+				return fmt.Errorf("failed to send span events: %w", err)
+			}
+		}
+	}
+
+	// Span links go to their own measurement when configured
+	if e.config.SpanLinksMeasurement != "" {
+		linksPayload, err := e.spanLinksToColumnar(td)
+		if err != nil {
+			return fmt.Errorf("failed to convert span links: %w", err)
+		}
+		if linksPayload != nil {
+			if err := e.sendToArc(ctx, database, linksPayload); err != nil {
+				return fmt.Errorf("failed to send span links: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// splitByMeasurement groups spans by the measurement resolved from the traces
+// measurement template. Static measurement names return the traces unchanged.
+func (e *tracesExporter) splitByMeasurement(td ptrace.Traces) map[string]ptrace.Traces {
+	template := e.measurement
+	if template.static {
+		return map[string]ptrace.Traces{template.fallback: td}
+	}
+
+	result := make(map[string]ptrace.Traces)
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		destResources := make(map[string]ptrace.ResourceSpans)
+
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			destScopes := make(map[string]ptrace.ScopeSpans)
+
+			for k := 0; k < ss.Spans().Len(); k++ {
+				span := ss.Spans().At(k)
+				measurement := template.resolve(rs.Resource().Attributes(), span.Attributes())
+
+				// Copy resource and scope once per measurement, then the span itself
+				destScope, ok := destScopes[measurement]
+				if !ok {
+					destResource, ok := destResources[measurement]
+					if !ok {
+						traces, ok := result[measurement]
+						if !ok {
+							traces = ptrace.NewTraces()
+							result[measurement] = traces
+						}
+						destResource = traces.ResourceSpans().AppendEmpty()
+						rs.Resource().CopyTo(destResource.Resource())
+						destResource.SetSchemaUrl(rs.SchemaUrl())
+						destResources[measurement] = destResource
+					}
+					destScope = destResource.ScopeSpans().AppendEmpty()
+					ss.Scope().CopyTo(destScope.Scope())
+					destScope.SetSchemaUrl(ss.SchemaUrl())
+					destScopes[measurement] = destScope
+				}
+				span.CopyTo(destScope.Spans().AppendEmpty())
+			}
+		}
+	}
+	return result
+}
+
+func (e *tracesExporter) tracesToColumnar(td ptrace.Traces, measurement string) ([]byte, error) {
 	// Columnar arrays for fixed fields
 	times := []int64{}
+	endTimes := []int64{}
 	traceIDs := []string{}
 	spanIDs := []string{}
 	parentSpanIDs := []string{}
+	traceStates := []string{}
+	flags := []uint32{}
 	serviceNames := []string{}
 	operationNames := []string{}
 	spanKinds := []string{}
 	durationsNs := []int64{}
 	statusCodes := []int32{}
 	statusMessages := []string{}
+	scopeNames := []string{}
+	scopeVersions := []string{}
+	droppedAttributesCounts := []uint32{}
+	droppedEventsCounts := []uint32{}
+	droppedLinksCounts := []uint32{}
 
 	// Collect all attributes for dynamic columns
 	allAttributes := []map[string]interface{}{}
@@ -65,16 +219,29 @@ func (e *tracesExporter) tracesToColumnar(td ptrace.Traces) ([]byte, error) {
 		// Get resource attributes
 		resourceAttrs := attributesToMap(rs.Resource().Attributes())
 
+		// With normalized resources, rows only carry the resource ID
+		rowResourceAttrs := resourceAttrs
+		if e.resources != nil {
+			rowResourceAttrs = map[string]interface{}{resourceIDColumn: resourceID(resourceAttrs)}
+		}
+
 		// Iterate through scope spans
 		for j := 0; j < rs.ScopeSpans().Len(); j++ {
 			ss := rs.ScopeSpans().At(j)
 
+			// Scope attributes are only included if requested
+			baseAttrs := rowResourceAttrs
+			if e.config.IncludeScopeAttributes {
+				baseAttrs = mergeAttributes(rowResourceAttrs, scopeAttributesToMap(ss.Scope()))
+			}
+
 			// Iterate through spans
 			for k := 0; k < ss.Spans().Len(); k++ {
 				span := ss.Spans().At(k)
 
-				// Time (Arc expects milliseconds)
-				times = append(times, span.StartTimestamp().AsTime().UnixMilli())
+				// Time in the configured precision
+				times = append(times, toArcTime(span.StartTimestamp(), e.config.TimestampPrecision))
+				endTimes = append(endTimes, toArcTime(span.EndTimestamp(), e.config.TimestampPrecision))
 
 				// IDs
 				traceIDs = append(traceIDs, span.TraceID().String())
@@ -86,6 +253,12 @@ func (e *tracesExporter) tracesToColumnar(td ptrace.Traces) ([]byte, error) {
 				}
 				parentSpanIDs = append(parentSpanIDs, parentSpanID)
 
+				// W3C tracestate
+				traceStates = append(traceStates, span.TraceState().AsRaw())
+
+				// W3C trace flags (sampled bit) and OTLP span flags
+				flags = append(flags, span.Flags())
+
 				// Service name from resource attributes
 				serviceName := ""
 				if sn, ok := resourceAttrs["service.name"]; ok {
@@ -109,78 +282,174 @@ func (e *tracesExporter) tracesToColumnar(td ptrace.Traces) ([]byte, error) {
 				statusCodes = append(statusCodes, int32(span.Status().Code()))
 				statusMessages = append(statusMessages, span.Status().Message())
 
+				// Instrumentation scope
+				scopeNames = append(scopeNames, ss.Scope().Name())
+				scopeVersions = append(scopeVersions, ss.Scope().Version())
+
+				// Data dropped by the SDK (attribute, event and link limits)
+				droppedAttributesCounts = append(droppedAttributesCounts, span.DroppedAttributesCount())
+				droppedEventsCounts = append(droppedEventsCounts, span.DroppedEventsCount())
+				droppedLinksCounts = append(droppedLinksCounts, span.DroppedLinksCount())
+
 				// Merge resource attributes with span attributes
 				spanAttrs := attributesToMap(span.Attributes())
-				mergedAttrs := mergeAttributes(resourceAttrs, spanAttrs)
+				mergedAttrs := mergeAttributes(baseAttrs, spanAttrs)
+				// Skip service.name since we already have service_name column
+				delete(mergedAttrs, "service.name")
 				allAttributes = append(allAttributes, mergedAttrs)
 			}
 		}
 	}
 
-	// Dynamically extract all unique attribute keys
-	attributeKeys := make(map[string]bool)
-	for _, attrs := range allAttributes {
-		for key := range attrs {
-			// Skip service.name since we already have service_name column
-			if key != "service.name" {
-				attributeKeys[key] = true
-			}
-		}
-	}
-
 	// Create columns map with fixed fields
 	columns := map[string]interface{}{
-		"time":           times,
-		"trace_id":       traceIDs,
-		"span_id":        spanIDs,
-		"parent_span_id": parentSpanIDs,
-		"service_name":   serviceNames,
-		"operation_name": operationNames,
-		"span_kind":      spanKinds,
-		"duration_ns":    durationsNs,
-		"status_code":    statusCodes,
-		"status_message": statusMessages,
+		"time":                     times,
+		"end_time":                 endTimes,
+		"trace_id":                 traceIDs,
+		"span_id":                  spanIDs,
+		"parent_span_id":           parentSpanIDs,
+		"trace_state":              traceStates,
+		"flags":                    flags,
+		"service_name":             serviceNames,
+		"operation_name":           operationNames,
+		"span_kind":                spanKinds,
+		"duration_ns":              durationsNs,
+		"status_code":              statusCodes,
+		"status_message":           statusMessages,
+		"scope_name":               scopeNames,
+		"scope_version":            scopeVersions,
+		"dropped_attributes_count": droppedAttributesCounts,
+		"dropped_events_count":     droppedEventsCounts,
+		"dropped_links_count":      droppedLinksCounts,
 	}
 
-	// Add dynamic columns for each attribute
-	for attrKey := range attributeKeys {
-		columnValues := make([]interface{}, len(allAttributes))
-		for i, attrs := range allAttributes {
-			if val, ok := attrs[attrKey]; ok {
-				columnValues[i] = val
-			} else {
-				columnValues[i] = nil
+	// Add attribute columns according to the attributes mode; the resource ID stays a column
+	addAttributeColumns(e.config, columns, allAttributes, resourceIDKeys)
+
+	return encodeColumnar(e.config, measurement, columns)
+}
+
+// spanEventsToColumnar converts span events (e.g. exceptions) into one row per event.
+// It returns nil if the traces contain no events.
+func (e *tracesExporter) spanEventsToColumnar(td ptrace.Traces) ([]byte, error) {
+	times := []int64{}
+	traceIDs := []string{}
+	spanIDs := []string{}
+	serviceNames := []string{}
+	eventNames := []string{}
+
+	// Event attributes become dynamic columns
+	allAttributes := []map[string]interface{}{}
+
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+
+		serviceName := ""
+		if sn, ok := rs.Resource().Attributes().Get("service.name"); ok {
+			serviceName = sn.AsString()
+		}
+
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+
+			for k := 0; k < ss.Spans().Len(); k++ {
+				span := ss.Spans().At(k)
+
+				for l := 0; l < span.Events().Len(); l++ {
+					event := span.Events().At(l)
+
+					times = append(times, toArcTime(event.Timestamp(), e.config.TimestampPrecision))
+					traceIDs = append(traceIDs, span.TraceID().String())
+					spanIDs = append(spanIDs, span.SpanID().String())
+					serviceNames = append(serviceNames, serviceName)
+					eventNames = append(eventNames, event.Name())
+					allAttributes = append(allAttributes, attributesToMap(event.Attributes()))
+				}
 			}
 		}
-		columns[attrKey] = columnValues
 	}
 
-	// Create columnar payload
-	columnarData := map[string]interface{}{
-		"m":       e.config.TracesMeasurement,
-		"columns": columns,
+	if len(times) == 0 {
+		return nil, nil
 	}
 
-	// Serialize to msgpack
-	msgpackData, err := msgpack.Marshal(columnarData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal msgpack: %w", err)
+	columns := map[string]interface{}{
+		"time":         times,
+		"trace_id":     traceIDs,
+		"span_id":      spanIDs,
+		"service_name": serviceNames,
+		"event_name":   eventNames,
 	}
+	addAttributeColumns(e.config, columns, allAttributes, nil)
+
+	return encodeColumnar(e.config, e.config.SpanEventsMeasurement, columns)
+}
+
+// spanLinksToColumnar converts span links into one row per link so linked traces
+// (batch consumers, fan-in messaging) can be reconnected. It returns nil if the
+// traces contain no links.
+func (e *tracesExporter) spanLinksToColumnar(td ptrace.Traces) ([]byte, error) {
+	times := []int64{}
+	traceIDs := []string{}
+	spanIDs := []string{}
+	serviceNames := []string{}
+	linkedTraceIDs := []string{}
+	linkedSpanIDs := []string{}
+	linkedTraceStates := []string{}
+
+	// Link attributes become dynamic columns
+	allAttributes := []map[string]interface{}{}
 
-	// Compress with gzip
-	var buf bytes.Buffer
-	gzipWriter := gzip.NewWriter(&buf)
-	if _, err := gzipWriter.Write(msgpackData); err != nil {
-		return nil, fmt.Errorf("failed to compress: %w", err)
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+
+		serviceName := ""
+		if sn, ok := rs.Resource().Attributes().Get("service.name"); ok {
+			serviceName = sn.AsString()
+		}
+
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+
+			for k := 0; k < ss.Spans().Len(); k++ {
+				span := ss.Spans().At(k)
+
+				for l := 0; l < span.Links().Len(); l++ {
+					link := span.Links().At(l)
+
+					// Links have no timestamp of their own, use the span start time
+					times = append(times, toArcTime(span.StartTimestamp(), e.config.TimestampPrecision))
+					traceIDs = append(traceIDs, span.TraceID().String())
+					spanIDs = append(spanIDs, span.SpanID().String())
+					serviceNames = append(serviceNames, serviceName)
+					linkedTraceIDs = append(linkedTraceIDs, link.TraceID().String())
+					linkedSpanIDs = append(linkedSpanIDs, link.SpanID().String())
+					linkedTraceStates = append(linkedTraceStates, link.TraceState().AsRaw())
+					allAttributes = append(allAttributes, attributesToMap(link.Attributes()))
+				}
+			}
+		}
 	}
-	if err := gzipWriter.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+
+	if len(times) == 0 {
+		return nil, nil
 	}
 
-	return buf.Bytes(), nil
+	columns := map[string]interface{}{
+		"time":               times,
+		"trace_id":           traceIDs,
+		"span_id":            spanIDs,
+		"service_name":       serviceNames,
+		"linked_trace_id":    linkedTraceIDs,
+		"linked_span_id":     linkedSpanIDs,
+		"linked_trace_state": linkedTraceStates,
+	}
+	addAttributeColumns(e.config, columns, allAttributes, nil)
+
+	return encodeColumnar(e.config, e.config.SpanLinksMeasurement, columns)
 }
 
-func (e *tracesExporter) sendToArc(ctx context.Context, payload []byte) error {
+func (e *tracesExporter) sendToArc(ctx context.Context, database string, payload []byte) error {
 	url := fmt.Sprintf("%s/api/v1/write/msgpack", e.config.Endpoint)
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(payload))
@@ -189,8 +458,11 @@ func (e *tracesExporter) sendToArc(ctx context.Context, payload []byte) error {
 	}
 
 	req.Header.Set("Content-Type", "application/msgpack")
-	req.Header.Set("Content-Encoding", "gzip")
-	req.Header.Set("X-Arc-Database", e.config.TracesDatabase)
+	if encoding := contentEncoding(e.config.Compression); encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	req.Header.Set("X-Arc-Timestamp-Precision", e.config.TimestampPrecision)
+	req.Header.Set("X-Arc-Database", database)
 
 	if e.config.AuthToken != "" {
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.config.AuthToken))
@@ -202,9 +474,8 @@ func (e *tracesExporter) sendToArc(ctx context.Context, payload []byte) error {
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("arc returned status %d: %s", resp.StatusCode, string(body))
+	if err := checkArcResponse(resp, e.logger); err != nil {
+		return err
 	}
 
 	e.logger.Debug("Successfully sent traces to Arc",
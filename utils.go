@@ -1,5 +1,39 @@
 package arcexporter
 
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configcompression"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// startClient prepares an exporter for sending: it builds the shared zstd encoder up
+// front rather than on the first payload, and the HTTP client from the confighttp
+// settings so TLS, proxy, headers and auth extensions are applied
+func startClient(config *Config, host component.Host, settings component.TelemetrySettings) (*http.Client, error) {
+	if config.Compression == configcompression.TypeZstd {
+		if _, err := zstdEncoder(config.CompressionLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	// Payloads are compressed by the exporter, so the client must not compress again
+	clientSettings := config.ClientConfig
+	clientSettings.Compression = ""
+	client, err := clientSettings.ToClient(host, settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+	return client, nil
+}
+
 // mergeAttributes merges resource attributes with signal-specific attributes
 // Signal-specific attributes take precedence over resource attributes
 func mergeAttributes(resourceAttrs, signalAttrs map[string]interface{}) map[string]interface{} {
@@ -17,3 +51,132 @@ func mergeAttributes(resourceAttrs, signalAttrs map[string]interface{}) map[stri
 
 	return result
 }
+
+// addAttributeColumns adds the attributes of each row to columns according to the configured
+// attributes mode:
+//   - "columns": one dynamic column per unique attribute key
+//   - "json": a single attributes column holding each row's attributes serialized as JSON
+//   - "hybrid": promoted attribute keys become columns, the rest go to the attributes column
+//
+// Keys listed in fixedKeys are always stored as columns regardless of the mode. Keys that
+// collide with an existing (fixed) column are kept in the attributes column instead.
+func addAttributeColumns(config *Config, columns map[string]interface{}, rows []map[string]interface{}, fixedKeys map[string]bool) {
+	// Decide per key whether it becomes its own column
+	asColumn := func(key string) bool {
+		switch config.AttributesMode {
+		case attributesModeJSON:
+			return fixedKeys[key]
+		case attributesModeHybrid:
+			return fixedKeys[key] || config.promotedAttributes[key]
+		default:
+			return true
+		}
+	}
+
+	attributeKeys := make(map[string]bool)
+	hasJSON := false
+	for _, attrs := range rows {
+		for key := range attrs {
+			if _, fixed := columns[key]; fixed {
+				hasJSON = true
+				continue
+			}
+			if asColumn(key) {
+				attributeKeys[key] = true
+			} else {
+				hasJSON = true
+			}
+		}
+	}
+
+	for attrKey := range attributeKeys {
+		columnValues := make([]interface{}, len(rows))
+		for i, attrs := range rows {
+			if val, ok := attrs[attrKey]; ok {
+				columnValues[i] = val
+			} else {
+				columnValues[i] = nil
+			}
+		}
+		columns[attrKey] = columnValues
+	}
+
+	if !hasJSON {
+		return
+	}
+
+	// Remaining attributes are serialized into a single JSON column
+	jsonValues := make([]interface{}, len(rows))
+	for i, attrs := range rows {
+		remaining := make(map[string]interface{})
+		for key, val := range attrs {
+			if !attributeKeys[key] {
+				remaining[key] = val
+			}
+		}
+		jsonValues[i] = attributesToJSON(remaining)
+	}
+	columns[attributesColumn] = jsonValues
+}
+
+// attributesToJSON serializes attributes to a JSON string, returning nil for an empty map.
+// Values JSON cannot represent (NaN, Inf) are stored as strings.
+func attributesToJSON(attrs map[string]interface{}) interface{} {
+	if len(attrs) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(attrs)
+	if err != nil {
+		for key, val := range attrs {
+			if f, ok := val.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+				attrs[key] = strconv.FormatFloat(f, 'g', -1, 64)
+			}
+		}
+		if data, err = json.Marshal(attrs); err != nil {
+			return nil
+		}
+	}
+	return string(data)
+}
+
+// encodeColumnar serializes a measurement in Arc's columnar msgpack format and
+// compresses it with the configured encoding
+func encodeColumnar(config *Config, measurement string, columns map[string]interface{}) ([]byte, error) {
+	columnarData := map[string]interface{}{
+		"m":       measurement,
+		"columns": columns,
+	}
+
+	msgpackData, err := msgpack.Marshal(columnarData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal msgpack: %w", err)
+	}
+
+	return compressPayload(msgpackData, config.Compression, config.CompressionLevel)
+}
+
+// toArcTime converts an OTel timestamp to an integer in the configured precision
+// ("ns", "us", "ms" or "s"). Milliseconds are used if precision is unset.
+func toArcTime(ts pcommon.Timestamp, precision string) int64 {
+	switch precision {
+	case timestampPrecisionNanoseconds:
+		return int64(ts)
+	case timestampPrecisionMicroseconds:
+		return int64(ts) / int64(time.Microsecond)
+	case timestampPrecisionSeconds:
+		return int64(ts) / int64(time.Second)
+	default:
+		return int64(ts) / int64(time.Millisecond)
+	}
+}
+
+// scopeAttributesToMap returns the instrumentation scope attributes with a "scope."
+// prefix so they do not collide with resource or signal attributes
+func scopeAttributesToMap(scope pcommon.InstrumentationScope) map[string]interface{} {
+	result := make(map[string]interface{}, scope.Attributes().Len())
+	scope.Attributes().Range(func(k string, v pcommon.Value) bool {
+		result["scope."+k] = valueToInterface(v)
+		return true
+	})
+	return result
+}